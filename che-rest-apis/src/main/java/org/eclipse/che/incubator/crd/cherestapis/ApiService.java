@@ -37,7 +37,6 @@ import org.eclipse.che.api.workspace.server.model.impl.devfile.DevfileImpl;
 
 import io.kubernetes.client.ApiClient;
 import io.kubernetes.client.ApiException;
-import io.kubernetes.client.Configuration;
 import io.kubernetes.client.apis.CustomObjectsApi;
 import io.kubernetes.client.util.Config;
 import io.quarkus.runtime.StartupEvent;
@@ -175,7 +174,15 @@ public class ApiService {
     }
 
     private Map<String, Object> retrieveWorkspaceCustomResource() throws ApiException {
-        return asMap(new CustomObjectsApi().getNamespacedCustomObject("workspace.che.eclipse.org", workspaceCrdVersion, workspaceNamespace,
+        // The ServiceAccount token may be a projected token that the kubelet rotates,
+        // so a new client reads it again on each request
+        ApiClient client;
+        try {
+            client = Config.defaultClient();
+        } catch(IOException e) {
+            throw new RuntimeException("Kubernetes client cannot be created", e);
+        }
+        return asMap(new CustomObjectsApi(client).getNamespacedCustomObject("workspace.che.eclipse.org", workspaceCrdVersion, workspaceNamespace,
                 "workspaces", workspaceName));
     }
 
@@ -216,12 +223,6 @@ public class ApiService {
 
     void init() {
         devfileIntegrityValidator = new DevfileIntegrityValidator(ImmutableMap.of());
-        try {
-            ApiClient client = Config.defaultClient();
-            Configuration.setDefaultApiClient(client);
-        } catch(IOException e) {
-            throw new RuntimeException("Kubernetes client cannot be created", e);
-        }
     }
 
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"github.com/che-incubator/che-workspace-crd-operator/pkg/controller/workspace"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// che-workspace-render prints the K8s objects that the controller would create for a Workspace,
+// as a multi-document YAML stream, without connecting to any cluster.
+//
+// Usage:
+//
+//	che-workspace-render --workspace workspace.yaml --config controller-config.yaml [--registry-dir ./plugin-registry] [--openshift]
+func main() {
+	var workspaceFile, configFile, registryDir, namespace, outputFile string
+	var isOpenshift bool
+	pflag.StringVarP(&workspaceFile, "workspace", "w", "", "YAML file of the Workspace to render")
+	pflag.StringVarP(&configFile, "config", "c", "", "YAML file of the controller ConfigMap")
+	pflag.StringVar(&registryDir, "registry-dir", "", "Local directory that serves as the plugin registry, with the plugins/{publisher}/{name}/{version}/meta.yaml layout")
+	pflag.StringVarP(&namespace, "namespace", "n", "default", "Namespace of the Workspace when it doesn't set one")
+	pflag.StringVarP(&outputFile, "output", "o", "", "File to write the objects to, instead of the standard output")
+	pflag.BoolVar(&isOpenshift, "openshift", false, "Render the objects for an OpenShift cluster")
+	pflag.Parse()
+
+	if err := render(workspaceFile, configFile, registryDir, namespace, outputFile, isOpenshift); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func render(workspaceFile, configFile, registryDir, namespace, outputFile string, isOpenshift bool) error {
+	if workspaceFile == "" || configFile == "" {
+		return errors.New("Both the --workspace and --config files are required")
+	}
+
+	wksp := &workspaceApi.Workspace{}
+	if err := readYaml(workspaceFile, wksp); err != nil {
+		return err
+	}
+	if wksp.Namespace == "" {
+		wksp.Namespace = namespace
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := readYaml(configFile, configMap); err != nil {
+		return err
+	}
+	if registryDir != "" {
+		absRegistryDir, err := filepath.Abs(registryDir)
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data["plugin.registry"] = "file://" + absRegistryDir
+	}
+	workspace.SetupRenderConfig(configMap, isOpenshift)
+
+	if err := workspace.SetupDownloadCache(); err != nil {
+		return err
+	}
+	defer workspace.CleanDownloadCache()
+
+	objects, err := workspace.RenderWorkspace(wksp)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if outputFile != "" {
+		out, err = os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	return workspace.WriteObjectsYaml(objects, out)
+}
+
+func readYaml(file string, obj interface{}) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(content, obj); err != nil {
+		return errors.New("Invalid YAML in file '" + file + "': " + err.Error())
+	}
+	return nil
+}
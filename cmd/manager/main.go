@@ -32,6 +32,8 @@ import (
 var (
 	metricsHost       = "0.0.0.0"
 	metricsPort int32 = 8383
+	// Port of the server of the plugin extensions of local and private registries
+	extensionsPort int32 = 8090
 )
 var log = logf.Log.WithName("cmd")
 
@@ -96,7 +98,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	workspace.SetupDownloadCache()
+	if err := workspace.SetupDownloadCache(); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
 	defer workspace.CleanDownloadCache()
 
 	log.Info("Registering Components.")
@@ -115,6 +120,22 @@ func main() {
 		log.Info(err.Error())
 	}
 
+	log.Info("Expose Plugin Extensions Port.")
+
+	// Create Service object to expose the plugin extensions to the workspace pods.
+	_, err = registry.ExposeExtensionsPort(ctx, extensionsPort)
+	if err != nil {
+		log.Info(err.Error())
+	}
+	if err := workspace.SetupExtensionsServerKey(); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	if err := mgr.Add(workspace.NewExtensionsServer(extensionsPort)); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	log.Info("Setting up Controllers.")
 
 	// Setup all Controllers
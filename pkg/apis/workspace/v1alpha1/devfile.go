@@ -1,27 +1,33 @@
 package v1alpha1
 
-import (
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-)
-
-// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
-
-// This schema describes the structure of the devfile object
+// This schema describes the structure of the devfile object, as defined by the devfile 1.0 format
 type DevFileSpec struct {
-	metav1.TypeMeta   `json:",inline"`
-	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Version of the devfile format. Only `1.0.0` is supported
+	ApiVersion string `json:"apiVersion,omitempty"`
+	// Metadata of the devfile
+	Metadata DevfileMetadata `json:"metadata,omitempty"`
+	// Additional devfile attributes, such as `persistVolumes` and `editorFree`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Parent devfile whose components, commands and projects are merged into this devfile
+	Parent *DevfileSource `json:"parent,omitempty"`
 	// List of workspace-wide commands that can be associated to a given component, in order to run in the related container
-	Commands          []CommandSpec   `json:"commands,omitempty"` // Description of the predefined commands to be available in workspace
+	Commands []CommandSpec `json:"commands,omitempty"` // Description of the predefined commands to be available in workspace
 	// List of projects that should be opened in the workspace
-	Projects          []ProjectSpec   `json:"projects,omitempty"` // Description of the projects, containing names and sources locations
+	Projects []ProjectSpec `json:"projects,omitempty"` // Description of the projects, containing names and sources locations
 	// List of components (containers, plugins, ...) that will provide the workspace features
-	Components        []ComponentSpec `json:"components"`         // Description of the workspace components, such as editor and plugins
+	Components []ComponentSpec `json:"components,omitempty"` // Description of the workspace components, such as editor and plugins
+}
+
+type DevfileMetadata struct {
+	Name         string `json:"name,omitempty"`         // The name of the devfile workspace
+	GenerateName string `json:"generateName,omitempty"` // Prefix of the generated workspace name, used when the name is not set
 }
 
 type CommandSpec struct {
-	Actions    []CommandActionSpec `json:"actions,omitempty"`    // List of the actions of given command. Now the only one command must be specified in list; but there are plans to implement supporting multiple actions commands.
+	Actions    []CommandActionSpec `json:"actions,omitempty"`    // List of the actions of given command. Several actions are run in sequence as a composite command.
 	Attributes map[string]string   `json:"attributes,omitempty"` // Additional command attributes
 	Name       string              `json:"name"`                 // Describes the name of the command. Should be unique per commands set.
+	PreviewUrl *PreviewUrl         `json:"previewUrl,omitempty"` // The URL to open to preview the result of the command
 }
 
 type CommandActionSpec struct {
@@ -33,45 +39,90 @@ type CommandActionSpec struct {
 	ReferenceContent *string `json:"referenceContent,omitempty"` // Working directory where the command should be executed
 }
 
+// Describes the URL that the user should open to preview the result of a command
+type PreviewUrl struct {
+	Port int64  `json:"port"`           // The port of the endpoint to preview
+	Path string `json:"path,omitempty"` // The path to open on the endpoint
+}
+
 type ProjectSpec struct {
-	Name   string            `json:"name"`
-	Source ProjectSourceSpec `json:"source"` // Describes the project's source - type and location
+	Name      string            `json:"name"`
+	Source    ProjectSourceSpec `json:"source"`              // Describes the project's source - type and location
+	ClonePath string            `json:"clonePath,omitempty"` // Path relative to the projects root where the project is cloned. Defaults to the project name.
 }
 
 // Describes the project's source - type and location
 type ProjectSourceSpec struct {
-	Location string `json:"location"` // Project's source location address. Should be URL for git and github located projects, or; file:// for zip.
-	Type     string `json:"type"`     // Project's source type.
+	Location          string `json:"location"`                    // Project's source location address. Should be URL for git and github located projects, or; file:// for zip.
+	Type              string `json:"type"`                        // Project's source type.
+	Branch            string `json:"branch,omitempty"`            // The name of the branch to check out after the project is cloned
+	StartPoint        string `json:"startPoint,omitempty"`        // The tag or commit id to reset the checked out branch to
+	Tag               string `json:"tag,omitempty"`               // The tag to check out after the project is cloned
+	CommitId          string `json:"commitId,omitempty"`          // The commit id to check out after the project is cloned
+	SparseCheckoutDir string `json:"sparseCheckoutDir,omitempty"` // The only directory of the repository to check out
 }
 
 type ComponentSpec struct {
-	Endpoints        []Endpoint        `json:"endpoints,omitempty"`        // Describes dockerimage component endpoints
-	Env              []Env             `json:"env,omitempty"`              // The environment variables list that should be set to docker container
-	Id               *string           `json:"id,omitempty"`               // Describes the component FQN
-	Image            *string           `json:"image,omitempty"`            // Specifies the docker image that should be used for component
-	Reference        *string           `json:"reference,omitempty"`        // Describes location of Kubernetes list yaml file. Applicable only for 'kubernetes' and; 'openshift' type components
-	ReferenceContent *string           `json:"referenceContent,omitempty"` // Inlined content of a file specified in field 'local'
-	MemoryLimit      *string           `json:"memoryLimit,omitempty"`      // Describes memory limit for the component. You can express memory as a plain integer or as a; fixed-point integer using one of these suffixes: E, P, T, G, M, K. You can also use the; power-of-two equivalents: Ei, Pi, Ti, Gi, Mi, Ki
-	MountSources     *bool             `json:"mountSources,omitempty"`     // Describes whether projects sources should be mount to the component. `CHE_PROJECTS_ROOT`; environment variable should contains a path where projects sources are mount
-	Alias            *string           `json:"alias,omitempty"`            // Describes the name of the component. Should be unique per component set.
-	Selector         map[string]string `json:"selector,omitempty"`         // Describes the objects selector for the recipe type components. Allows to pick-up only selected; items from k8s/openshift list
-	Type             DevfileName       `json:"type"`                       // Describes type of the component, e.g. whether it is an plugin or editor or other type
-	Volumes          []Volume          `json:"volumes,omitempty"`          // Describes volumes which should be mount to component
-	Command          *[]string         `json:"command,omitempty"`          // The command to run in the dockerimage component instead of the default one provided in the image. Defaults to null, meaning use whatever is defined in the image.
-	Args             *[]string         `json:"args,omitempty"`             // The arguments to supply to the command running the dockerimage component. The arguments are supplied either to the default command provided in the image or to the overridden command. Defaults to null, meaning use whatever is defined in the image.
+	Endpoints                 []Endpoint        `json:"endpoints,omitempty"`                 // Describes dockerimage component endpoints
+	Env                       []Env             `json:"env,omitempty"`                       // The environment variables list that should be set to docker container
+	EnvFrom                   []EnvFromSource   `json:"envFrom,omitempty"`                   // Secrets of the workspace namespace whose keys should all be set as environment variables of the containers
+	Id                        *string           `json:"id,omitempty"`                        // Describes the component FQN
+	Image                     *string           `json:"image,omitempty"`                     // Specifies the docker image that should be used for component
+	Reference                 *string           `json:"reference,omitempty"`                 // Describes location of Kubernetes list yaml file. Applicable only for 'kubernetes' and; 'openshift' type components
+	ReferenceContent          *string           `json:"referenceContent,omitempty"`          // Inlined content of a file specified in field 'local'
+	MemoryLimit               *string           `json:"memoryLimit,omitempty"`               // Describes memory limit for the component. You can express memory as a plain integer or as a; fixed-point integer using one of these suffixes: E, P, T, G, M, K. You can also use the; power-of-two equivalents: Ei, Pi, Ti, Gi, Mi, Ki
+	MountSources              *bool             `json:"mountSources,omitempty"`              // Describes whether projects sources should be mount to the component. `CHE_PROJECTS_ROOT`; environment variable should contains a path where projects sources are mount
+	Alias                     *string           `json:"alias,omitempty"`                     // Describes the name of the component. Should be unique per component set.
+	Selector                  map[string]string `json:"selector,omitempty"`                  // Describes the objects selector for the recipe type components. Allows to pick-up only selected; items from k8s/openshift list
+	Type                      DevfileName       `json:"type"`                                // Describes type of the component, e.g. whether it is an plugin or editor or other type
+	Volumes                   []Volume          `json:"volumes,omitempty"`                   // Describes volumes which should be mount to component
+	Command                   *[]string         `json:"command,omitempty"`                   // The command to run in the dockerimage component instead of the default one provided in the image. Defaults to null, meaning use whatever is defined in the image.
+	Args                      *[]string         `json:"args,omitempty"`                      // The arguments to supply to the command running the dockerimage component. The arguments are supplied either to the default command provided in the image or to the overridden command. Defaults to null, meaning use whatever is defined in the image.
+	RegistryUrl               *string           `json:"registryUrl,omitempty"`               // The plugin registry of the chePlugin or cheEditor component, used when the id doesn't contain one
+	AutomountWorkspaceSecrets *bool             `json:"automountWorkspaceSecrets,omitempty"` // Describes whether the secrets of the namespace labelled for automount should be mounted to the containers of the component
+	Entrypoints               []Entrypoint      `json:"entrypoints,omitempty"`               // Overrides of the command and arguments of the containers of kubernetes and openshift components
+}
+
+// Describes the command and arguments of the containers matched by the entrypoint.
+// Containers are matched by the name or labels of the top-level object that contains them, and by their name.
+type Entrypoint struct {
+	ParentName     string            `json:"parentName,omitempty"`     // The name of the top-level object that contains the containers
+	ParentSelector map[string]string `json:"parentSelector,omitempty"` // The labels of the top-level objects that contain the containers
+	ContainerName  string            `json:"containerName,omitempty"`  // The name of the container
+	Command        *[]string         `json:"command,omitempty"`        // The command to run in the matched containers instead of the default one
+	Args           *[]string         `json:"args,omitempty"`           // The arguments to supply to the command running in the matched containers
 }
 
 // Describes dockerimage component endpoint
 type Endpoint struct {
-	Attributes map[string]string   `json:"attributes,omitempty"`
-	Name       string                     `json:"name"` // The endpoint name
-	Port       int64                      `json:"port"` // The endpoint port
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Name       string            `json:"name"` // The endpoint name
+	Port       int64             `json:"port"` // The endpoint port
 }
 
 // Describes environment variable
 type Env struct {
-	Name  string `json:"name"`  // The environment variable name
-	Value string `json:"value"` // The environment variable value
+	Name      string        `json:"name"`                // The environment variable name
+	Value     string        `json:"value,omitempty"`     // The environment variable value
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"` // The source of the environment variable value, used instead of the value
+}
+
+// Describes the Secret or ConfigMap key that contains the value of an environment variable
+type EnvVarSource struct {
+	SecretKeyRef    *KeySelector `json:"secretKeyRef,omitempty"`    // The key of a Secret of the workspace namespace
+	ConfigMapKeyRef *KeySelector `json:"configMapKeyRef,omitempty"` // The key of a ConfigMap of the workspace namespace
+}
+
+// Selects a key of a Secret or ConfigMap of the workspace namespace
+type KeySelector struct {
+	Name string `json:"name"` // The name of the Secret or ConfigMap
+	Key  string `json:"key"`  // The key to select
+}
+
+// Describes a Secret of the workspace namespace whose keys are all set as environment variables
+type EnvFromSource struct {
+	SecretName string `json:"secretName"`       // The name of the Secret
+	Prefix     string `json:"prefix,omitempty"` // The prefix added to the name of each environment variable
 }
 
 // Describe volume that should be mount to component
@@ -89,3 +140,14 @@ const (
 	Kubernetes  DevfileName = "kubernetes"
 	Openshift   DevfileName = "openshift"
 )
+
+// Version of the devfile format supported by the controller
+const DevfileApiVersion = "1.0.0"
+
+const (
+	// Devfile attribute that makes the workspace use ephemeral storage instead of the workspace persistent volume claim
+	// when set to "false"
+	PersistVolumesAttribute = "persistVolumes"
+	// Devfile attribute that disables the injection of the default editor when set to "true"
+	EditorFreeAttribute = "editorFree"
+)
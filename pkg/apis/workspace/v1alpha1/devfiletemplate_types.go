@@ -0,0 +1,47 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// DevfileTemplateSpec defines a curated devfile that workspaces can be based on
+// +k8s:openapi-gen=true
+type DevfileTemplateSpec struct {
+	// Name of the template displayed to users
+	DisplayName string `json:"displayName,omitempty"`
+	// Description of the stack provided by the template
+	Description string `json:"description,omitempty"`
+	// URL of the icon of the template
+	Icon string `json:"icon,omitempty"`
+	// Devfile that workspaces based on the template inherit from
+	Devfile DevFileSpec `json:"devfile"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DevfileTemplate is the Schema for the cluster-wide devfile templates API
+// +k8s:openapi-gen=true
+// +genclient:nonNamespaced
+// +kubebuilder:printcolumn:name=Display Name,type=string,JSONPath=.spec.displayName
+// +kubebuilder:printcolumn:name=Description,type=string,JSONPath=.spec.description
+type DevfileTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DevfileTemplateSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DevfileTemplateList contains a list of DevfileTemplate
+type DevfileTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DevfileTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DevfileTemplate{}, &DevfileTemplateList{})
+}
@@ -83,6 +83,29 @@ type WorkspaceStatus struct {
 	Members MembersStatus `json:"members"`
 	// URL at which the Editor can be joined
 	IdeUrl string `json:"ideUrl,omitempty"`
+	// Human-readable message about the current state of the workspace,
+	// such as the reason why it has no IDE URL
+	Message string `json:"message,omitempty"`
+	// SHA-256 hash of the devfile resolved from the `devfileSource` of the workspace
+	DevfileSourceHash string `json:"devfileSourceHash,omitempty"`
+	// Parent of the devfile of the workspace, merged with its own parents, in the YAML format
+	FlattenedDevfileParent string `json:"flattenedDevfileParent,omitempty"`
+	// Source of the flattened devfile parent, in the JSON format
+	FlattenedDevfileParentSource string `json:"flattenedDevfileParentSource,omitempty"`
+	// Secrets, ConfigMaps and keys referenced by the env of the workspace components that don't exist
+	// in the workspace namespace
+	MissingReferences []string `json:"missingReferences,omitempty"`
 	// AdditionalInfo
 	AdditionalInfo map[string]string `json:"additionalFields,omitempty"`
+	// Last time the workspace entered the Running phase
+	LastStartTime *metav1.Time `json:"lastStartTime,omitempty"`
+	// Last time the workspace left the Running phase
+	LastStopTime *metav1.Time `json:"lastStopTime,omitempty"`
+	// Time the workspace entered the Running phase, unset while the workspace is not running
+	RunningSince *metav1.Time `json:"runningSince,omitempty"`
+	// Cumulative time, in seconds, the workspace has spent in the Running phase.
+	// The current run is only added when the workspace leaves the Running phase.
+	RunningSeconds int64 `json:"runningSeconds,omitempty"`
+	// Number of times the workspace was started from the Stopped or Failed phase
+	StartCount int32 `json:"startCount,omitempty"`
 }
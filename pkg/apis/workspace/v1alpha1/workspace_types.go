@@ -17,6 +17,7 @@ import (
 // +kubebuilder:printcolumn:name=Enabled,type=boolean,JSONPath=.spec.started
 // +kubebuilder:printcolumn:name=Status,type=string,JSONPath=.status.phase
 // +kubebuilder:printcolumn:name=Url,type=string,JSONPath=.status.ideUrl
+// +kubebuilder:printcolumn:name=Uptime,type=date,JSONPath=.status.runningSince
 type Workspace struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -36,7 +37,48 @@ type WorkspaceSpec struct {
 	ExposureClass string  `json:"exposureClass,omitempty"`
 	// Workspace Structure defined in the Devfile format syntax.
 	// For more details see the Che 7 documentation: https://www.eclipse.org/che/docs/che-7/making-a-workspace-portable-using-a-devfile/
-	Devfile DevFileSpec   `json:"devfile"`
+	Devfile DevFileSpec   `json:"devfile,omitempty"`
+	// Reference to a devfile stored outside of the workspace, used instead of the inlined devfile.
+	// The resolved devfile is only fetched again when the source changes, or when the value of the
+	// `org.eclipse.che.workspace/refresh-devfile` annotation changes.
+	DevfileSource *DevfileSource `json:"devfileSource,omitempty"`
+	// Name of the DevfileTemplate that the devfile of the workspace inherits from, as if it was its parent.
+	// Changes of the template are only taken into account when the workspace starts.
+	Template string `json:"template,omitempty"`
+	// Names of the Secrets of the workspace namespace used to pull the images of the workspace,
+	// in addition to the ones of the `image.pull.secrets` property of the controller configuration
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+}
+
+// DevfileSource references a devfile by HTTP URL, by git repository, by ConfigMap or by DevfileTemplate.
+// Exactly one of its fields should be set.
+type DevfileSource struct {
+	// HTTP or HTTPS URL of the devfile
+	Url string `json:"url,omitempty"`
+	// Git repository that contains the devfile
+	Git *GitDevfileSource `json:"git,omitempty"`
+	// ConfigMap of the workspace namespace that contains the devfile
+	ConfigMap *ConfigMapDevfileSource `json:"configMap,omitempty"`
+	// Name of the DevfileTemplate that contains the devfile
+	Template string `json:"template,omitempty"`
+}
+
+// GitDevfileSource references a devfile stored in a GitHub or GitLab repository
+type GitDevfileSource struct {
+	// HTTP or HTTPS URL of the repository
+	Repository string `json:"repository"`
+	// Branch, tag or commit of the devfile. Defaults to the default branch of the repository
+	Revision string `json:"revision,omitempty"`
+	// Path of the devfile in the repository. Defaults to `devfile.yaml`
+	Path string `json:"path,omitempty"`
+}
+
+// ConfigMapDevfileSource references a devfile stored in a ConfigMap
+type ConfigMapDevfileSource struct {
+	// Name of the ConfigMap
+	Name string `json:"name"`
+	// Key of the ConfigMap that contains the devfile. Defaults to `devfile.yaml`
+	Key string `json:"key,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
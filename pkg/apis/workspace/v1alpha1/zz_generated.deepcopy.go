@@ -66,6 +66,11 @@ func (in *CommandSpec) DeepCopyInto(out *CommandSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.PreviewUrl != nil {
+		in, out := &in.PreviewUrl, &out.PreviewUrl
+		*out = new(PreviewUrl)
+		**out = **in
+	}
 	return
 }
 
@@ -92,6 +97,13 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make([]Env, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]EnvFromSource, len(*in))
 		copy(*out, *in)
 	}
 	if in.Id != nil {
@@ -159,6 +171,23 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 			copy(*out, *in)
 		}
 	}
+	if in.RegistryUrl != nil {
+		in, out := &in.RegistryUrl, &out.RegistryUrl
+		*out = new(string)
+		**out = **in
+	}
+	if in.AutomountWorkspaceSecrets != nil {
+		in, out := &in.AutomountWorkspaceSecrets, &out.AutomountWorkspaceSecrets
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Entrypoints != nil {
+		in, out := &in.Entrypoints, &out.Entrypoints
+		*out = make([]Entrypoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -172,11 +201,38 @@ func (in *ComponentSpec) DeepCopy() *ComponentSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapDevfileSource) DeepCopyInto(out *ConfigMapDevfileSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapDevfileSource.
+func (in *ConfigMapDevfileSource) DeepCopy() *ConfigMapDevfileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapDevfileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevFileSpec) DeepCopyInto(out *DevFileSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Metadata = in.Metadata
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Parent != nil {
+		in, out := &in.Parent, &out.Parent
+		*out = new(DevfileSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Commands != nil {
 		in, out := &in.Commands, &out.Commands
 		*out = make([]CommandSpec, len(*in))
@@ -209,14 +265,125 @@ func (in *DevFileSpec) DeepCopy() *DevFileSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevfileMetadata) DeepCopyInto(out *DevfileMetadata) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevfileMetadata.
+func (in *DevfileMetadata) DeepCopy() *DevfileMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(DevfileMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevfileSource) DeepCopyInto(out *DevfileSource) {
+	*out = *in
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = new(GitDevfileSource)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ConfigMapDevfileSource)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevfileSource.
+func (in *DevfileSource) DeepCopy() *DevfileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DevfileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevfileTemplate) DeepCopyInto(out *DevfileTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevfileTemplate.
+func (in *DevfileTemplate) DeepCopy() *DevfileTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(DevfileTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DevfileTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevfileTemplateList) DeepCopyInto(out *DevfileTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DevfileTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevfileTemplateList.
+func (in *DevfileTemplateList) DeepCopy() *DevfileTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(DevfileTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DevFileSpec) DeepCopyObject() runtime.Object {
+func (in *DevfileTemplateList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevfileTemplateSpec) DeepCopyInto(out *DevfileTemplateSpec) {
+	*out = *in
+	in.Devfile.DeepCopyInto(&out.Devfile)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevfileTemplateSpec.
+func (in *DevfileTemplateSpec) DeepCopy() *DevfileTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DevfileTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 	*out = *in
@@ -240,9 +407,55 @@ func (in *Endpoint) DeepCopy() *Endpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Entrypoint) DeepCopyInto(out *Entrypoint) {
+	*out = *in
+	if in.ParentSelector != nil {
+		in, out := &in.ParentSelector, &out.ParentSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = new([]string)
+		if **in != nil {
+			in, out := *in, *out
+			*out = make([]string, len(*in))
+			copy(*out, *in)
+		}
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = new([]string)
+		if **in != nil {
+			in, out := *in, *out
+			*out = make([]string, len(*in))
+			copy(*out, *in)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Entrypoint.
+func (in *Entrypoint) DeepCopy() *Entrypoint {
+	if in == nil {
+		return nil
+	}
+	out := new(Entrypoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Env) DeepCopyInto(out *Env) {
 	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(EnvVarSource)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -256,6 +469,48 @@ func (in *Env) DeepCopy() *Env {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvFromSource) DeepCopyInto(out *EnvFromSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvFromSource.
+func (in *EnvFromSource) DeepCopy() *EnvFromSource {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvFromSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvVarSource) DeepCopyInto(out *EnvVarSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(KeySelector)
+		**out = **in
+	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(KeySelector)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVarSource.
+func (in *EnvVarSource) DeepCopy() *EnvVarSource {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvVarSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExposedEndpoint) DeepCopyInto(out *ExposedEndpoint) {
 	*out = *in
@@ -279,6 +534,38 @@ func (in *ExposedEndpoint) DeepCopy() *ExposedEndpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitDevfileSource) DeepCopyInto(out *GitDevfileSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitDevfileSource.
+func (in *GitDevfileSource) DeepCopy() *GitDevfileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GitDevfileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeySelector) DeepCopyInto(out *KeySelector) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeySelector.
+func (in *KeySelector) DeepCopy() *KeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(KeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MembersStatus) DeepCopyInto(out *MembersStatus) {
 	*out = *in
@@ -305,6 +592,22 @@ func (in *MembersStatus) DeepCopy() *MembersStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviewUrl) DeepCopyInto(out *PreviewUrl) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewUrl.
+func (in *PreviewUrl) DeepCopy() *PreviewUrl {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviewUrl)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectSourceSpec) DeepCopyInto(out *ProjectSourceSpec) {
 	*out = *in
@@ -583,6 +886,16 @@ func (in *WorkspaceList) DeepCopyObject() runtime.Object {
 func (in *WorkspaceSpec) DeepCopyInto(out *WorkspaceSpec) {
 	*out = *in
 	in.Devfile.DeepCopyInto(&out.Devfile)
+	if in.DevfileSource != nil {
+		in, out := &in.DevfileSource, &out.DevfileSource
+		*out = new(DevfileSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -607,6 +920,11 @@ func (in *WorkspaceStatus) DeepCopyInto(out *WorkspaceStatus) {
 		}
 	}
 	in.Members.DeepCopyInto(&out.Members)
+	if in.MissingReferences != nil {
+		in, out := &in.MissingReferences, &out.MissingReferences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.AdditionalInfo != nil {
 		in, out := &in.AdditionalInfo, &out.AdditionalInfo
 		*out = make(map[string]string, len(*in))
@@ -614,6 +932,18 @@ func (in *WorkspaceStatus) DeepCopyInto(out *WorkspaceStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.LastStartTime != nil {
+		in, out := &in.LastStartTime, &out.LastStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastStopTime != nil {
+		in, out := &in.LastStopTime, &out.LastStopTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RunningSince != nil {
+		in, out := &in.RunningSince, &out.RunningSince
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
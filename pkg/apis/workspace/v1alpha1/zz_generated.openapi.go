@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 // This file was autogenerated by openapi-gen. Do not edit it manually!
@@ -11,6 +12,8 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
+		"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevfileTemplate":         schema_pkg_apis_workspace_v1alpha1_DevfileTemplate(ref),
+		"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevfileTemplateSpec":     schema_pkg_apis_workspace_v1alpha1_DevfileTemplateSpec(ref),
 		"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.Workspace":               schema_pkg_apis_workspace_v1alpha1_Workspace(ref),
 		"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.WorkspaceExposure":       schema_pkg_apis_workspace_v1alpha1_WorkspaceExposure(ref),
 		"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.WorkspaceExposureSpec":   schema_pkg_apis_workspace_v1alpha1_WorkspaceExposureSpec(ref),
@@ -19,6 +22,86 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 	}
 }
 
+func schema_pkg_apis_workspace_v1alpha1_DevfileTemplate(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DevfileTemplate is the Schema for the cluster-wide devfile templates API",
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevfileTemplateSpec"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevfileTemplateSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_workspace_v1alpha1_DevfileTemplateSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DevfileTemplateSpec defines a curated devfile that workspaces can be based on",
+				Properties: map[string]spec.Schema{
+					"displayName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the template displayed to users",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"description": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Description of the stack provided by the template",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"icon": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URL of the icon of the template",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"devfile": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Devfile that workspaces based on the template inherit from",
+							Ref:         ref("github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevFileSpec"),
+						},
+					},
+				},
+				Required: []string{"devfile"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevFileSpec"},
+	}
+}
+
 func schema_pkg_apis_workspace_v1alpha1_Workspace(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -236,11 +319,24 @@ func schema_pkg_apis_workspace_v1alpha1_WorkspaceSpec(ref common.ReferenceCallba
 							Ref:         ref("github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevFileSpec"),
 						},
 					},
+					"devfileSource": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Reference to a devfile stored outside of the workspace, used instead of the inlined devfile. The resolved devfile is only fetched again when the source changes, or when the value of the `org.eclipse.che.workspace/refresh-devfile` annotation changes.",
+							Ref:         ref("github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevfileSource"),
+						},
+					},
+					"template": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the DevfileTemplate that the devfile of the workspace inherits from, as if it was its parent. Changes of the template are only taken into account when the workspace starts.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
-				Required: []string{"started", "devfile"},
+				Required: []string{"started"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevFileSpec"},
+			"github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevFileSpec", "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1.DevfileSource"},
 	}
 }
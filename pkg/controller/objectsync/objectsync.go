@@ -0,0 +1,421 @@
+package objectsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("objectsync")
+
+// Patch type of server-side apply requests
+const applyPatchType = types.PatchType("application/apply-patch+yaml")
+
+// Prefix of the annotation that stores the configuration last applied by a field manager,
+// used when the API server doesn't support server-side apply.
+const lastAppliedConfigAnnotationPrefix = "objectsync.che.eclipse.org/last-applied-"
+
+// Kinds of objects that cannot be updated once created
+var immutableKinds = map[schema.GroupKind]struct{}{
+	schema.GroupKind{Group: "batch", Kind: "Job"}: {},
+}
+
+// Outcome of the synchronization of a single object
+type Outcome int
+
+const (
+	Unchanged Outcome = iota
+	Created
+	Updated
+)
+
+// Result counts the outcomes of the synchronization of several objects
+type Result struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+func (r *Result) Add(outcome Outcome) {
+	switch outcome {
+	case Created:
+		r.Created++
+	case Updated:
+		r.Updated++
+	default:
+		r.Unchanged++
+	}
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d unchanged", r.Created, r.Updated, r.Unchanged)
+}
+
+// Syncer applies the objects built by a controller with server-side apply, under the controller field manager,
+// so that fields defaulted by the API server or set by other controllers are left untouched.
+// When the API server doesn't support server-side apply, it falls back to a client-side three-way merge
+// based on the configuration last applied by the field manager.
+type Syncer struct {
+	fieldManager string
+	scheme       *runtime.Scheme
+	config       *rest.Config
+	client       client.Client
+	restClient   rest.Interface
+
+	mapper          meta.RESTMapper
+	serverSideApply bool
+	mux             sync.Mutex
+}
+
+// New creates a Syncer that applies objects with the given field manager
+func New(mgr manager.Manager, fieldManager string) (*Syncer, error) {
+	apiClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig := rest.CopyConfig(mgr.GetConfig())
+	restConfig.GroupVersion = &schema.GroupVersion{Version: "v1"}
+	restConfig.APIPath = "/api"
+	restConfig.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(mgr.GetScheme())}
+	restClient, err := rest.RESTClientFor(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper, err := apiutil.NewDiscoveryRESTMapper(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Syncer{
+		fieldManager:    fieldManager,
+		scheme:          mgr.GetScheme(),
+		config:          mgr.GetConfig(),
+		client:          apiClient,
+		restClient:      restClient,
+		mapper:          mapper,
+		serverSideApply: true,
+	}, nil
+}
+
+// Client returns the non-cached client used by the Syncer, which supports unstructured objects
+func (s *Syncer) Client() client.Client {
+	return s.client
+}
+
+// ToUnstructured converts a typed object to an unstructured object with its kind set
+func (s *Syncer) ToUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, isUnstructured := obj.(*unstructured.Unstructured); isUnstructured {
+		return u, nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, s.scheme)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
+
+// Apply creates the object, or updates the fields of the existing object that the field manager sets.
+// An existing object that is not controlled by the same owner as the desired object is never taken over,
+// and objects of immutable kinds are only created.
+func (s *Syncer) Apply(obj runtime.Object) (Outcome, error) {
+	desired, err := s.ToUnstructured(obj)
+	if err != nil {
+		return Unchanged, err
+	}
+	delete(desired.Object, "status")
+	pruneNilValues(desired.Object)
+	unstructured.RemoveNestedField(desired.Object, "metadata", "creationTimestamp")
+
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(desired.GroupVersionKind())
+	err = s.client.Get(context.TODO(), types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, found)
+	if k8sErrors.IsNotFound(err) {
+		found = nil
+	} else if err != nil {
+		return Unchanged, err
+	}
+
+	if found != nil {
+		if _, isImmutable := immutableKinds[desired.GroupVersionKind().GroupKind()]; isImmutable {
+			return Unchanged, nil
+		}
+		if desiredOwner := metav1.GetControllerOf(desired); desiredOwner != nil {
+			if foundOwner := metav1.GetControllerOf(found); foundOwner == nil || foundOwner.UID != desiredOwner.UID {
+				return Unchanged, errors.New("Cannot take over existing " + desired.GetKind() + " '" + desired.GetName() + "' that is not controlled by " + desiredOwner.Kind + " '" + desiredOwner.Name + "'")
+			}
+		}
+	}
+
+	if s.isServerSideApplySupported() {
+		applied, err := s.serverSideApply(desired)
+		if err == nil {
+			switch {
+			case found == nil:
+				return Created, nil
+			case applied.GetResourceVersion() != found.GetResourceVersion():
+				return Updated, nil
+			}
+			return Unchanged, nil
+		}
+		if !k8sErrors.IsUnsupportedMediaType(err) {
+			return Unchanged, err
+		}
+		log.Info("Server-side apply is not supported by the API server: falling back to client-side apply", "fieldManager", s.fieldManager)
+		s.disableServerSideApply()
+	}
+	return s.clientSideApply(desired, found)
+}
+
+// Delete deletes an object and lets the garbage collector delete its dependents in the background
+func (s *Syncer) Delete(obj runtime.Object) error {
+	err := s.client.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if k8sErrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *Syncer) isServerSideApplySupported() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.serverSideApply
+}
+
+func (s *Syncer) disableServerSideApply() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.serverSideApply = false
+}
+
+func (s *Syncer) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	mapping, err := s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// The kind may have been added to the cluster since the mapper was built
+		mapper, mapperErr := apiutil.NewDiscoveryRESTMapper(s.config)
+		if mapperErr != nil {
+			return nil, mapperErr
+		}
+		s.mapper = mapper
+		mapping, err = s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	return mapping, err
+}
+
+func (s *Syncer) serverSideApply(desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	mapping, err := s.restMapping(desired.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(desired.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	path := []string{"/apis", mapping.GroupVersionKind.Group, mapping.GroupVersionKind.Version}
+	if mapping.GroupVersionKind.Group == "" {
+		path = []string{"/api", mapping.GroupVersionKind.Version}
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		path = append(path, "namespaces", desired.GetNamespace())
+	}
+	path = append(path, mapping.Resource.Resource, desired.GetName())
+
+	raw, err := s.restClient.Patch(applyPatchType).
+		AbsPath(path...).
+		Param("fieldManager", s.fieldManager).
+		Param("force", "true").
+		Body(body).
+		Do().
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+	applied := &unstructured.Unstructured{}
+	err = applied.UnmarshalJSON(raw)
+	return applied, err
+}
+
+// clientSideApply creates the object, or merges the desired fields into the existing object,
+// removing the fields that the field manager applied previously but doesn't set anymore.
+func (s *Syncer) clientSideApply(desired *unstructured.Unstructured, found *unstructured.Unstructured) (Outcome, error) {
+	annotationName := lastAppliedConfigAnnotationPrefix + s.fieldManager
+	appliedConfig, err := json.Marshal(desired.Object)
+	if err != nil {
+		return Unchanged, err
+	}
+	annotations := desired.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[annotationName] = string(appliedConfig)
+	desired.SetAnnotations(annotations)
+
+	if found == nil {
+		return Created, s.client.Create(context.TODO(), desired)
+	}
+
+	lastApplied := map[string]interface{}{}
+	if lastAppliedConfig, exists := found.GetAnnotations()[annotationName]; exists {
+		if err := json.Unmarshal([]byte(lastAppliedConfig), &lastApplied); err != nil {
+			log.Error(err, "Ignoring the invalid last applied configuration", "kind", desired.GetKind(), "name", desired.GetName())
+		}
+	}
+
+	merged := found.DeepCopy()
+	mergeApplied(merged.Object, lastApplied, desired.Object)
+	if reflect.DeepEqual(merged.Object, found.Object) {
+		return Unchanged, nil
+	}
+	return Updated, s.client.Update(context.TODO(), merged)
+}
+
+// Fields that identify the elements of lists of objects, by order of preference
+var listMergeKeys = []string{"name", "mountPath", "containerPort", "port", "ip"}
+
+// mergeApplied sets the desired fields into the current object content, and removes the fields
+// of the last applied configuration that are not desired anymore.
+func mergeApplied(current, lastApplied, desired map[string]interface{}) {
+	for key := range lastApplied {
+		if _, stillDesired := desired[key]; !stillDesired {
+			delete(current, key)
+		}
+	}
+	for key, desiredValue := range desired {
+		current[key] = mergeAppliedValue(current[key], lastApplied[key], desiredValue)
+	}
+}
+
+func mergeAppliedValue(current, lastApplied, desired interface{}) interface{} {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		currentMap, isMap := current.(map[string]interface{})
+		if !isMap {
+			return desiredValue
+		}
+		lastAppliedMap, _ := lastApplied.(map[string]interface{})
+		mergeApplied(currentMap, lastAppliedMap, desiredValue)
+		return currentMap
+	case []interface{}:
+		currentList, isList := current.([]interface{})
+		if !isList {
+			return desiredValue
+		}
+		lastAppliedList, _ := lastApplied.([]interface{})
+		return mergeAppliedList(currentList, lastAppliedList, desiredValue)
+	}
+	return desired
+}
+
+// mergeAppliedList merges lists of objects element by element, using the first field that identifies
+// all the desired elements. Elements added by others are kept, and other lists are replaced.
+func mergeAppliedList(current, lastApplied, desired []interface{}) []interface{} {
+	mergeKey := listMergeKey(desired)
+	if mergeKey == "" || listMergeKey(current) != mergeKey {
+		return desired
+	}
+
+	currentByKey := map[interface{}]map[string]interface{}{}
+	for _, element := range current {
+		elementMap := element.(map[string]interface{})
+		currentByKey[elementMap[mergeKey]] = elementMap
+	}
+	lastAppliedByKey := map[interface{}]map[string]interface{}{}
+	for _, element := range lastApplied {
+		if elementMap, isMap := element.(map[string]interface{}); isMap {
+			lastAppliedByKey[elementMap[mergeKey]] = elementMap
+		}
+	}
+
+	merged := []interface{}{}
+	desiredKeys := map[interface{}]bool{}
+	for _, element := range desired {
+		desiredElement := element.(map[string]interface{})
+		key := desiredElement[mergeKey]
+		desiredKeys[key] = true
+		if currentElement, exists := currentByKey[key]; exists {
+			mergeApplied(currentElement, lastAppliedByKey[key], desiredElement)
+			merged = append(merged, currentElement)
+		} else {
+			merged = append(merged, desiredElement)
+		}
+	}
+	for _, element := range current {
+		key := element.(map[string]interface{})[mergeKey]
+		if _, wasApplied := lastAppliedByKey[key]; !desiredKeys[key] && !wasApplied {
+			merged = append(merged, element)
+		}
+	}
+	return merged
+}
+
+// listMergeKey returns the field that uniquely identifies each element of a list of objects,
+// or an empty string if there is none.
+func listMergeKey(list []interface{}) string {
+	if len(list) == 0 {
+		return ""
+	}
+	for _, mergeKey := range listMergeKeys {
+		values := map[interface{}]bool{}
+		for _, element := range list {
+			elementMap, isMap := element.(map[string]interface{})
+			if !isMap {
+				return ""
+			}
+			value, exists := elementMap[mergeKey]
+			if !exists || values[value] {
+				break
+			}
+			values[value] = true
+		}
+		if len(values) == len(list) {
+			return mergeKey
+		}
+	}
+	return ""
+}
+
+// pruneNilValues removes the null fields produced by the conversion of typed objects,
+// which would otherwise remove the values defaulted by the API server.
+func pruneNilValues(content map[string]interface{}) {
+	for key, value := range content {
+		switch typedValue := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			pruneNilValues(typedValue)
+		case []interface{}:
+			for _, element := range typedValue {
+				if elementMap, isMap := element.(map[string]interface{}); isMap {
+					pruneNilValues(elementMap)
+				}
+			}
+		}
+	}
+}
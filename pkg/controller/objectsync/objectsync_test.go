@@ -0,0 +1,203 @@
+package objectsync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectsClient is a client that stores a single object
+type objectsClient struct {
+	client.Client
+	object  *unstructured.Unstructured
+	created bool
+	updated bool
+}
+
+func (c *objectsClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if c.object == nil {
+		return k8sErrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+	}
+	c.object.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (c *objectsClient) Create(ctx context.Context, obj runtime.Object) error {
+	c.object = obj.(*unstructured.Unstructured).DeepCopy()
+	c.created = true
+	return nil
+}
+
+func (c *objectsClient) Update(ctx context.Context, obj runtime.Object) error {
+	c.object = obj.(*unstructured.Unstructured).DeepCopy()
+	c.updated = true
+	return nil
+}
+
+func newConfigMap(t *testing.T, data string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: parseContent(t, `{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {"name": "workspace", "namespace": "che"},
+		"data": `+data+`
+	}`)}
+}
+
+func TestApplyFallsBackToClientSideApply(t *testing.T) {
+	patches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		patches++
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	}))
+	defer server.Close()
+	restClient, err := rest.RESTClientFor(&rest.Config{
+		Host:    server.URL,
+		APIPath: "/api",
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &schema.GroupVersion{Version: "v1"},
+			NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(runtime.NewScheme())},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Version: "v1"}})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	objects := &objectsClient{}
+	syncer := &Syncer{
+		fieldManager:    "controller",
+		client:          objects,
+		restClient:      restClient,
+		mapper:          mapper,
+		serverSideApply: true,
+	}
+
+	outcome, err := syncer.Apply(newConfigMap(t, `{"a": "1", "b": "1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome != Created || !objects.created || patches != 1 {
+		t.Fatalf("Expected the object to be created after the server-side apply failed, got outcome %v", outcome)
+	}
+	if syncer.isServerSideApplySupported() {
+		t.Error("Expected server-side apply to be disabled once the API server doesn't support it")
+	}
+	if _, hasLastApplied := objects.object.GetAnnotations()[lastAppliedConfigAnnotationPrefix+"controller"]; !hasLastApplied {
+		t.Error("Expected the applied configuration to be stored in an annotation")
+	}
+
+	// Data set by another client is kept, and data not applied anymore is removed
+	unstructured.SetNestedField(objects.object.Object, "1", "data", "other")
+	outcome, err = syncer.Apply(newConfigMap(t, `{"a": "2"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedStringMap(objects.object.Object, "data")
+	if outcome != Updated || !objects.updated || !reflect.DeepEqual(data, map[string]string{"a": "2", "other": "1"}) {
+		t.Errorf("Expected the object to be merged with the applied configuration, got outcome %v and data %v", outcome, data)
+	}
+	if patches != 1 {
+		t.Errorf("Expected no other server-side apply request, got %d", patches)
+	}
+
+	outcome, err = syncer.Apply(newConfigMap(t, `{"a": "2"}`))
+	if err != nil || outcome != Unchanged {
+		t.Errorf("Expected the object to be unchanged, got outcome %v and error %v", outcome, err)
+	}
+}
+
+func parseContent(t *testing.T, content string) map[string]interface{} {
+	parsed := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestClientSideApplyKeepsFieldsSetByOthers(t *testing.T) {
+	current := parseContent(t, `{
+		"metadata": {"name": "workspace", "annotations": {"other": "value"}},
+		"spec": {"clusterIP": "10.0.0.1", "type": "ClusterIP", "sessionAffinity": "None"}
+	}`)
+	lastApplied := parseContent(t, `{
+		"metadata": {"name": "workspace"},
+		"spec": {"type": "ClusterIP", "sessionAffinity": "None"}
+	}`)
+	desired := parseContent(t, `{
+		"metadata": {"name": "workspace"},
+		"spec": {"type": "NodePort"}
+	}`)
+
+	mergeApplied(current, lastApplied, desired)
+
+	expected := parseContent(t, `{
+		"metadata": {"name": "workspace", "annotations": {"other": "value"}},
+		"spec": {"clusterIP": "10.0.0.1", "type": "NodePort"}
+	}`)
+	if !reflect.DeepEqual(current, expected) {
+		t.Errorf("Expected the fields set by others to be kept and the ones not applied anymore to be removed, got %v", current)
+	}
+}
+
+func TestClientSideApplyMergesListsByKey(t *testing.T) {
+	current := parseContent(t, `{"containers": [
+		{"name": "theia", "image": "theia:1", "terminationMessagePath": "/dev/termination-log"},
+		{"name": "removed", "image": "removed:1"},
+		{"name": "injected", "image": "proxy:1"}
+	]}`)
+	lastApplied := parseContent(t, `{"containers": [
+		{"name": "theia", "image": "theia:1"},
+		{"name": "removed", "image": "removed:1"}
+	]}`)
+	desired := parseContent(t, `{"containers": [
+		{"name": "theia", "image": "theia:2"},
+		{"name": "added", "image": "added:1"}
+	]}`)
+
+	mergeApplied(current, lastApplied, desired)
+
+	expected := parseContent(t, `{"containers": [
+		{"name": "theia", "image": "theia:2", "terminationMessagePath": "/dev/termination-log"},
+		{"name": "added", "image": "added:1"},
+		{"name": "injected", "image": "proxy:1"}
+	]}`)
+	if !reflect.DeepEqual(current, expected) {
+		t.Errorf("Expected the containers to be merged by name, got %v", current)
+	}
+}
+
+func TestClientSideApplyReplacesListsWithoutKey(t *testing.T) {
+	current := parseContent(t, `{"args": ["a", "b"]}`)
+	mergeApplied(current, parseContent(t, `{"args": ["a", "b"]}`), parseContent(t, `{"args": ["c"]}`))
+	if !reflect.DeepEqual(current["args"], []interface{}{"c"}) {
+		t.Errorf("Expected the list of values to be replaced, got %v", current["args"])
+	}
+}
+
+func TestPruneNilValues(t *testing.T) {
+	content := parseContent(t, `{
+		"metadata": {"name": "workspace", "creationTimestamp": null},
+		"spec": {"containers": [{"name": "theia", "resources": {"limits": null}}]}
+	}`)
+	pruneNilValues(content)
+
+	expected := parseContent(t, `{
+		"metadata": {"name": "workspace"},
+		"spec": {"containers": [{"name": "theia", "resources": {}}]}
+	}`)
+	if !reflect.DeepEqual(content, expected) {
+		t.Errorf("Expected the null fields to be removed, got %v", content)
+	}
+}
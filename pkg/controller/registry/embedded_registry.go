@@ -22,6 +22,10 @@ var log = logf.Log.WithName("che-plugin-registry")
 var trueVar = true
 var EmbeddedPluginRegistryUrl = ""
 
+// URL of the server through which the controller provides workspace pods with the plugin extensions
+// of local and private plugin registries
+var ExtensionsServerUrl = ""
+
 const (
 	// PrometheusPortName defines the port name used in the metrics Service.
 	PrometheusPortName = "metrics"
@@ -35,7 +39,7 @@ func ExposeRegistryPort(ctx context.Context, port int32) (*v1.Service, error) {
 	}
 	// We do not need to check the validity of the port, as controller-runtime
 	// would error out and we would never get to this stage.
-	s, err := initOperatorService(ctx, client, port, "registry")
+	s, err := initOperatorService(ctx, client, port, "registry", "-plugin-registry")
 	if err != nil {
 		if err == k8sutil.ErrNoNamespace {
 			log.Info("Skipping plugin registry Service creation; not running in a cluster.")
@@ -52,6 +56,29 @@ func ExposeRegistryPort(ctx context.Context, port int32) (*v1.Service, error) {
 	return service, nil
 }
 
+// ExposeExtensionsPort creates a Kubernetes Service to expose the passed port of the plugin extensions server.
+func ExposeExtensionsPort(ctx context.Context, port int32) (*v1.Service, error) {
+	client, err := createClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new client: %v", err)
+	}
+	s, err := initOperatorService(ctx, client, port, "extensions", "-plugin-extensions")
+	if err != nil {
+		if err == k8sutil.ErrNoNamespace {
+			log.Info("Skipping plugin extensions Service creation; not running in a cluster.")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to initialize service object for plugin extensions: %v", err)
+	}
+	service, err := createOrUpdateService(ctx, client, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create or get service for plugin extensions: %v", err)
+	}
+
+	ExtensionsServerUrl = fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", s.Name, s.Namespace, port)
+	return service, nil
+}
+
 func createOrUpdateService(ctx context.Context, client crclient.Client, s *v1.Service) (*v1.Service, error) {
 	if err := client.Create(ctx, s); err != nil {
 		if !apierrors.IsAlreadyExists(err) {
@@ -82,7 +109,7 @@ func createOrUpdateService(ctx context.Context, client crclient.Client, s *v1.Se
 }
 
 // initOperatorService returns the static service which exposes specified port.
-func initOperatorService(ctx context.Context, client crclient.Client, port int32, portName string) (*v1.Service, error) {
+func initOperatorService(ctx context.Context, client crclient.Client, port int32, portName string, nameSuffix string) (*v1.Service, error) {
 	operatorName, err := k8sutil.GetOperatorName()
 	if err != nil {
 		return nil, err
@@ -96,7 +123,7 @@ func initOperatorService(ctx context.Context, client crclient.Client, port int32
 
 	service := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      operatorName + "-plugin-registry",
+			Name:      operatorName + nameSuffix,
 			Namespace: namespace,
 			Labels:    label,
 		},
@@ -0,0 +1,253 @@
+package workspace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Default directory under which labelled Secrets and ConfigMaps are mounted as files
+const defaultAutomountDir = "/etc/che"
+
+// Directory under which the git credentials Secrets are mounted
+const gitCredentialsDir = "/.git-credentials"
+
+// Key of the git credentials Secrets that contains the credentials, in the format of the git `store` credential helper
+const gitCredentialsKey = "credentials"
+
+// automountResources are the volumes, volume mounts and env sources that mount labelled Secrets and ConfigMaps
+// to a workspace container
+type automountResources struct {
+	volumes      []corev1.Volume
+	volumeMounts []corev1.VolumeMount
+	envFrom      []corev1.EnvFromSource
+}
+
+// add registers a labelled Secret or ConfigMap, mounted as described by its `che.eclipse.org/mount-as` annotation.
+// It returns the path of the credentials file of git credentials Secrets.
+func (resources *automountResources) add(kind string, meta metav1.ObjectMeta, volumeSource corev1.VolumeSource, envFrom corev1.EnvFromSource) string {
+	volumeName := automountVolumeName(kind, meta.Name)
+	mountAs := meta.Annotations[AUTOMOUNT_AS_ANNOTATION]
+	switch mountAs {
+	case "env":
+		resources.envFrom = append(resources.envFrom, envFrom)
+		return ""
+	case "", "file":
+		mountPath := meta.Annotations[AUTOMOUNT_PATH_ANNOTATION]
+		if mountPath == "" {
+			mountPath = path.Join(defaultAutomountDir, meta.Name)
+		}
+		resources.volumes = append(resources.volumes, corev1.Volume{Name: volumeName, VolumeSource: volumeSource})
+		resources.volumeMounts = append(resources.volumeMounts, corev1.VolumeMount{Name: volumeName, MountPath: mountPath, ReadOnly: true})
+		return ""
+	case "git-credentials":
+		if kind != "secret" {
+			log.Info("Only Secrets can be mounted as git credentials, ignoring ConfigMap '" + meta.Name + "' in namespace '" + meta.Namespace + "'")
+			return ""
+		}
+		mountPath := path.Join(gitCredentialsDir, meta.Name)
+		resources.volumes = append(resources.volumes, corev1.Volume{Name: volumeName, VolumeSource: volumeSource})
+		resources.volumeMounts = append(resources.volumeMounts, corev1.VolumeMount{Name: volumeName, MountPath: mountPath, ReadOnly: true})
+		return path.Join(mountPath, gitCredentialsKey)
+	default:
+		log.Info("Unknown mount-as annotation '" + mountAs + "', ignoring " + kind + " '" + meta.Name + "' in namespace '" + meta.Namespace + "'")
+		return ""
+	}
+}
+
+// automountVolumeName returns the name of the volume of a labelled Secret or ConfigMap.
+// Object names may contain dots and be longer than volume names, so the name is based on a hash of the kind and name.
+func automountVolumeName(kind string, name string) string {
+	hash := sha256.Sum256([]byte(kind + "/" + name))
+	return "automount-" + hex.EncodeToString(hash[:])[:16]
+}
+
+func (resources *automountResources) mountTo(container *corev1.Container) {
+	container.VolumeMounts = append(container.VolumeMounts, resources.volumeMounts...)
+	container.EnvFrom = append(container.EnvFrom, resources.envFrom...)
+}
+
+// provisionAutomountObjects mounts the Secrets and ConfigMaps of the workspace namespace labelled with
+// `che.eclipse.org/mount-to-workspace=true` to the containers of the workspace pods, as files, env variables
+// or git credentials, according to their `che.eclipse.org/mount-as` annotation.
+// Objects annotated with `che.eclipse.org/automount-workspace-secret=false` are only mounted to the components
+// that set `automountWorkspaceSecrets` to true, and components that set it to false get no object at all.
+// The SSH keys and git configuration of the workspace user are mounted along with them.
+// The pod templates are annotated with a hash of the mounted object versions, so that changing them rolls out the workspace.
+// Nothing is mounted when there is no client to read the objects from, for example when rendering a workspace from a file.
+func provisionAutomountObjects(clt client.Client, workspace *workspaceApi.Workspace, wkspProps workspaceProperties, componentInstanceStatuses []ComponentInstanceStatus, k8sObjects []runtime.Object) ([]runtime.Object, error) {
+	if clt == nil || !wkspProps.started {
+		return k8sObjects, nil
+	}
+
+	listOptions := &client.ListOptions{
+		Namespace:     workspace.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{AUTOMOUNT_LABEL: "true"}),
+	}
+	secrets := &corev1.SecretList{}
+	if err := clt.List(context.TODO(), listOptions, secrets); err != nil {
+		return nil, err
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := clt.List(context.TODO(), listOptions, configMaps); err != nil {
+		return nil, err
+	}
+
+	defaultResources := &automountResources{}
+	optInResources := &automountResources{}
+	resourcesFor := func(meta metav1.ObjectMeta) *automountResources {
+		if meta.Annotations[AUTOMOUNT_WORKSPACE_SECRET_ANNOTATION] == "false" {
+			return optInResources
+		}
+		return defaultResources
+	}
+	gitCredentialFiles := []string{}
+	versionsHash := sha256.New()
+
+	for _, secret := range secrets.Items {
+		credentialsFile := resourcesFor(secret.ObjectMeta).add("secret", secret.ObjectMeta,
+			corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: secret.Name},
+			},
+			corev1.EnvFromSource{
+				SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}},
+			})
+		if credentialsFile != "" {
+			gitCredentialFiles = append(gitCredentialFiles, credentialsFile)
+		}
+		io.WriteString(versionsHash, "Secret/"+secret.Name+"/"+secret.ResourceVersion+"\n")
+	}
+	for _, configMap := range configMaps.Items {
+		resourcesFor(configMap.ObjectMeta).add("configmap", configMap.ObjectMeta,
+			corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name}},
+			},
+			corev1.EnvFromSource{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name}},
+			})
+		io.WriteString(versionsHash, "ConfigMap/"+configMap.Name+"/"+configMap.ResourceVersion+"\n")
+	}
+
+	gitConfigMap, err := provisionGitCredentials(clt, workspace, wkspProps, gitCredentialFiles, defaultResources, versionsHash)
+	if err != nil {
+		return nil, err
+	}
+	k8sObjects = append(k8sObjects, gitConfigMap)
+
+	containerSettings := containerAutomountSettings(componentInstanceStatuses)
+	hash := hex.EncodeToString(versionsHash.Sum(nil))
+	for _, obj := range k8sObjects {
+		podTemplate, _ := k8sLikePodTemplate(obj)
+		if podTemplate == nil {
+			continue
+		}
+		for containerIndex := range podTemplate.Spec.Containers {
+			container := &podTemplate.Spec.Containers[containerIndex]
+			automount := containerSettings[container.Name]
+			if automount != nil && !*automount {
+				continue
+			}
+			defaultResources.mountTo(container)
+			if automount != nil && *automount {
+				optInResources.mountTo(container)
+			}
+		}
+		podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, defaultResources.volumes...)
+		podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, optInResources.volumes...)
+		if podTemplate.Annotations == nil {
+			podTemplate.Annotations = map[string]string{}
+		}
+		podTemplate.Annotations[AUTOMOUNT_HASH_ANNOTATION] = hash
+	}
+	return k8sObjects, nil
+}
+
+// containerAutomountSettings returns the `automountWorkspaceSecrets` value of the component of each container
+// whose component sets it
+func containerAutomountSettings(componentInstanceStatuses []ComponentInstanceStatus) map[string]*bool {
+	settings := map[string]*bool{}
+	for _, componentInstanceStatus := range componentInstanceStatuses {
+		if componentInstanceStatus.AutomountWorkspaceSecrets == nil {
+			continue
+		}
+		for machineName := range componentInstanceStatus.Machines {
+			settings[machineName] = componentInstanceStatus.AutomountWorkspaceSecrets
+		}
+	}
+	return settings
+}
+
+// watchAutomountObjects reconciles the started workspaces of a namespace when one of its labelled Secrets
+// or ConfigMaps, or one of its user SSH keys or profile Secrets, changes
+func watchAutomountObjects(ctr controller.Controller, mgr manager.Manager) error {
+	var mapper handler.ToRequestsFunc = func(obj handler.MapObject) []reconcile.Request {
+		workspaces := &workspaceApi.WorkspaceList{}
+		err := mgr.GetClient().List(context.TODO(), &client.ListOptions{Namespace: obj.Meta.GetNamespace()}, workspaces)
+		if err != nil {
+			log.Error(err, "Cannot list the workspaces of namespace '"+obj.Meta.GetNamespace()+"'")
+			return []reconcile.Request{}
+		}
+		requests := []reconcile.Request{}
+		for _, workspace := range workspaces.Items {
+			if workspace.Spec.Started {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: workspace.Namespace,
+						Name:      workspace.Name,
+					},
+				})
+			}
+		}
+		return requests
+	}
+
+	isAutomounted := func(obj metav1.Object) bool {
+		return obj != nil && obj.GetLabels()[AUTOMOUNT_LABEL] == "true"
+	}
+	isUserSecret := func(obj metav1.Object, runtimeObj runtime.Object) bool {
+		_, isSecret := runtimeObj.(*corev1.Secret)
+		return isSecret && obj != nil && isUserSecretName(obj.GetName())
+	}
+	for _, obj := range []runtime.Object{
+		&corev1.Secret{},
+		&corev1.ConfigMap{},
+	} {
+		err := ctr.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: mapper,
+		}, predicate.Funcs{
+			UpdateFunc: func(evt event.UpdateEvent) bool {
+				return isAutomounted(evt.MetaOld) || isAutomounted(evt.MetaNew) || isUserSecret(evt.MetaNew, evt.ObjectNew)
+			},
+			CreateFunc: func(evt event.CreateEvent) bool {
+				return isAutomounted(evt.Meta) || isUserSecret(evt.Meta, evt.Object)
+			},
+			DeleteFunc: func(evt event.DeleteEvent) bool {
+				return isAutomounted(evt.Meta) || isUserSecret(evt.Meta, evt.Object)
+			},
+			GenericFunc: func(evt event.GenericEvent) bool {
+				return false
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestAutomountVolumeName(t *testing.T) {
+	longDottedName := "registry.example.com." + strings.Repeat("credentials", 20)
+	names := map[string]bool{}
+	for _, object := range [][2]string{
+		{"secret", "maven-settings"},
+		{"configmap", "maven-settings"},
+		{"secret", longDottedName},
+	} {
+		name := automountVolumeName(object[0], object[1])
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			t.Errorf("Expected a valid volume name for %s '%s', got '%s': %v", object[0], object[1], name, errs)
+		}
+		if name != automountVolumeName(object[0], object[1]) {
+			t.Errorf("Expected the volume name of %s '%s' to be stable", object[0], object[1])
+		}
+		names[name] = true
+	}
+	if len(names) != 3 {
+		t.Errorf("Expected the volume names of a Secret and a ConfigMap with the same name to differ, got %v", names)
+	}
+}
+
+func TestAutomountResources(t *testing.T) {
+	resources := &automountResources{}
+	volumeSource := corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "settings"}}
+	envFrom := corev1.EnvFromSource{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}}
+
+	resources.add("secret", metav1.ObjectMeta{Name: "settings"}, volumeSource, envFrom)
+	resources.add("secret", metav1.ObjectMeta{Name: "env", Annotations: map[string]string{AUTOMOUNT_AS_ANNOTATION: "env"}}, volumeSource, envFrom)
+	credentialsFile := resources.add("secret", metav1.ObjectMeta{Name: "git", Annotations: map[string]string{AUTOMOUNT_AS_ANNOTATION: "git-credentials"}}, volumeSource, envFrom)
+	resources.add("configmap", metav1.ObjectMeta{Name: "git", Annotations: map[string]string{AUTOMOUNT_AS_ANNOTATION: "git-credentials"}}, volumeSource, envFrom)
+
+	if len(resources.volumes) != 2 || len(resources.volumeMounts) != 2 || len(resources.envFrom) != 1 {
+		t.Fatalf("Expected 2 volumes and 1 env source, got %d volumes, %d volume mounts and %d env sources",
+			len(resources.volumes), len(resources.volumeMounts), len(resources.envFrom))
+	}
+	if resources.volumeMounts[0].MountPath != "/etc/che/settings" || resources.volumeMounts[0].Name != resources.volumes[0].Name {
+		t.Errorf("Expected the Secret to be mounted to the default directory, got %+v", resources.volumeMounts[0])
+	}
+	if credentialsFile != "/.git-credentials/git/credentials" {
+		t.Errorf("Expected the path of the git credentials file, got '%s'", credentialsFile)
+	}
+}
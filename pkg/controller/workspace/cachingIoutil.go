@@ -13,42 +13,427 @@
 package workspace
 
 import (
-	"sync"
-	"github.com/eclipse/che-plugin-broker/utils"
-	commonBroker "github.com/eclipse/che-plugin-broker/common"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net/http"
 	"os"
-	"io"
-	"path/filepath"	
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/che-plugin-broker/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	DownloadCacheDirEnvVar     = "DOWNLOAD_CACHE_DIR"
+	DownloadCacheMaxSizeEnvVar = "DOWNLOAD_CACHE_MAX_SIZE"
+)
+
+const (
+	downloadCacheIndexFile      = "index.json"
+	defaultDownloadCacheMaxSize = "1Gi"
+)
+
+var (
+	downloadCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "che_workspace_download_cache_hits_total",
+		Help: "Number of plugin downloads served from the download cache",
+	})
+	downloadCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "che_workspace_download_cache_misses_total",
+		Help: "Number of plugin downloads not found, or outdated, in the download cache",
+	})
+	downloadCacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "che_workspace_download_cache_evictions_total",
+		Help: "Number of entries evicted from the download cache to stay below its maximum size",
+	})
 )
 
+func init() {
+	metrics.Registry.MustRegister(downloadCacheHits, downloadCacheMisses, downloadCacheEvictions)
+}
+
+// Entry of the download cache index
+type cacheEntry struct {
+	// Path of the downloaded file, relative to the cache directory
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Checksum string    `json:"checksum"`
+	ETag     string    `json:"etag,omitempty"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
 type cache struct {
-	tempDir string
-	filenamesPerUrl map[string]string
-	random commonBroker.Random
-	mux sync.Mutex
+	dir     string
+	maxSize int64
+	// Whether the cache directory was configured, and should be kept when the controller stops
+	persistent bool
+	entries    map[string]*cacheEntry
+	// URLs whose entry is being verified or downloaded, without holding the lock of the cache
+	inFlight map[string]chan struct{}
+	mux      sync.Mutex
 }
 
 var downloadCache *cache
 
+// SetupDownloadCache initializes the cache of the plugin files downloaded by the controller,
+// such as the extensions of private registries served by the extensions server.
+// The cache is stored in the directory set by the `DOWNLOAD_CACHE_DIR` environment variable,
+// in which case its index is reloaded at startup, or in a temporary directory otherwise.
+// Its size is bounded by the `DOWNLOAD_CACHE_MAX_SIZE` environment variable, the least recently used
+// entries being evicted first.
 func SetupDownloadCache() error {
-	downloadTempDir, err := ioutil.TempDir("", "che-plugin-broker-httpcache")
+	maxSizeValue := defaultDownloadCacheMaxSize
+	if value, found := os.LookupEnv(DownloadCacheMaxSizeEnvVar); found && value != "" {
+		maxSizeValue = value
+	}
+	maxSize, err := resource.ParseQuantity(maxSizeValue)
 	if err != nil {
-		 return err
+		return err
+	}
+
+	downloadCache = &cache{
+		maxSize:  maxSize.Value(),
+		entries:  map[string]*cacheEntry{},
+		inFlight: map[string]chan struct{}{},
+	}
+
+	if cacheDir, found := os.LookupEnv(DownloadCacheDirEnvVar); found && cacheDir != "" {
+		err = os.MkdirAll(cacheDir, 0755)
+		if err != nil {
+			return err
+		}
+		downloadCache.dir = cacheDir
+		downloadCache.persistent = true
+		downloadCache.loadIndex()
+		return nil
 	}
 
-	downloadCache = &cache {
-		tempDir: downloadTempDir,
-		random: commonBroker.NewRand(),
-		filenamesPerUrl: map[string]string {},
+	downloadTempDir, err := ioutil.TempDir("", "che-plugin-broker-httpcache")
+	if err != nil {
+		return err
 	}
+	downloadCache.dir = downloadTempDir
 	return nil
 }
 
 func CleanDownloadCache() {
-	if downloadCache != nil {
-		os.RemoveAll(downloadCache.tempDir)
+	if downloadCache == nil {
+		return
 	}
+	if downloadCache.persistent {
+		downloadCache.mux.Lock()
+		defer downloadCache.mux.Unlock()
+		downloadCache.saveIndex()
+		return
+	}
+	os.RemoveAll(downloadCache.dir)
+}
+
+// loadIndex reloads the index of a persistent cache, dropping the entries whose file is missing or corrupted
+func (c *cache) loadIndex() {
+	content, err := ioutil.ReadFile(filepath.Join(c.dir, downloadCacheIndexFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Error(err, "Cannot read the download cache index")
+		}
+		return
+	}
+	entries := map[string]*cacheEntry{}
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		log.Error(err, "Ignoring the invalid download cache index")
+		return
+	}
+	for URL, entry := range entries {
+		entryDir, err := c.entryDir(entry)
+		if err != nil {
+			log.Error(err, "Ignoring the download cache entry of URL '"+URL+"'")
+			continue
+		}
+		if checksum, err := fileChecksum(filepath.Join(entryDir, filepath.Base(entry.Path))); err != nil || checksum != entry.Checksum {
+			os.RemoveAll(entryDir)
+			continue
+		}
+		c.entries[URL] = entry
+	}
+	c.removeOrphanEntryDirs()
+	log.Info(fmt.Sprintf("Loaded %d entries from the download cache in '%s'", len(c.entries), c.dir))
+}
+
+// entryDir returns the directory of a cache entry, which must be an `entry-*` directory directly
+// inside the cache directory, since the paths of the index file are not trusted
+func (c *cache) entryDir(entry *cacheEntry) (string, error) {
+	path := filepath.Clean(entry.Path)
+	dir, file := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if filepath.IsAbs(path) || file == "" || !strings.HasPrefix(dir, "entry-") || filepath.Dir(dir) != "." {
+		return "", fmt.Errorf("Invalid path '%s' of download cache entry", entry.Path)
+	}
+	return filepath.Join(c.dir, dir), nil
+}
+
+// removeEntryDir removes the directory of a cache entry
+func (c *cache) removeEntryDir(entry *cacheEntry) {
+	if entryDir, err := c.entryDir(entry); err == nil {
+		os.RemoveAll(entryDir)
+	}
+}
+
+// removeOrphanEntryDirs removes the entry directories that are not in the index anymore,
+// such as the ones of downloads interrupted by a restart of the controller
+func (c *cache) removeOrphanEntryDirs() {
+	indexed := map[string]bool{}
+	for _, entry := range c.entries {
+		if entryDir, err := c.entryDir(entry); err == nil {
+			indexed[filepath.Base(entryDir)] = true
+		}
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		log.Error(err, "Cannot list the download cache directory")
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() && strings.HasPrefix(file.Name(), "entry-") && !indexed[file.Name()] {
+			os.RemoveAll(filepath.Join(c.dir, file.Name()))
+		}
+	}
+}
+
+func (c *cache) saveIndex() {
+	if !c.persistent {
+		return
+	}
+	content, err := json.Marshal(c.entries)
+	if err != nil {
+		log.Error(err, "Cannot serialize the download cache index")
+		return
+	}
+	indexFile := filepath.Join(c.dir, downloadCacheIndexFile)
+	err = ioutil.WriteFile(indexFile+".tmp", content, 0644)
+	if err == nil {
+		err = os.Rename(indexFile+".tmp", indexFile)
+	}
+	if err != nil {
+		log.Error(err, "Cannot write the download cache index")
+	}
+}
+
+func (c *cache) remove(URL string) {
+	if entry, exists := c.entries[URL]; exists {
+		c.removeEntryDir(entry)
+		delete(c.entries, URL)
+	}
+}
+
+// evict removes the least recently used entries until the cache size is below its maximum size.
+// The entry of the given URL, that was just used, is never evicted.
+func (c *cache) evict(keptURL string) {
+	var totalSize int64
+	URLs := []string{}
+	for URL, entry := range c.entries {
+		totalSize += entry.Size
+		if URL != keptURL {
+			URLs = append(URLs, URL)
+		}
+	}
+	sort.Slice(URLs, func(i, j int) bool {
+		return c.entries[URLs[i]].LastUsed.Before(c.entries[URLs[j]].LastUsed)
+	})
+	for _, URL := range URLs {
+		if totalSize <= c.maxSize {
+			break
+		}
+		totalSize -= c.entries[URL].Size
+		log.V(1).Info("Evicting URL '" + URL + "' from the download cache")
+		c.remove(URL)
+		downloadCacheEvictions.Inc()
+	}
+}
+
+// openValid opens the file of a cached entry if it is still valid.
+// The checksum of the cached file is verified, and the entry is revalidated
+// against the server when it has an ETag.
+func (c *cache) openValid(URL string, entry *cacheEntry, headers http.Header) *os.File {
+	if entry == nil {
+		return nil
+	}
+	file, err := os.Open(filepath.Join(c.dir, entry.Path))
+	if err != nil {
+		return nil
+	}
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil || hex.EncodeToString(hash.Sum(nil)) != entry.Checksum {
+		log.Info("Dropping corrupted entry of URL '" + URL + "' from the download cache")
+		file.Close()
+		return nil
+	}
+	if entry.ETag != "" {
+		request, err := http.NewRequest(http.MethodHead, URL, nil)
+		if err != nil {
+			file.Close()
+			return nil
+		}
+		addHeaders(request, headers)
+		request.Header.Set("If-None-Match", entry.ETag)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			// Keep using the cached file when the server cannot be reached
+			return file
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusNotModified && response.Header.Get("ETag") != entry.ETag {
+			file.Close()
+			return nil
+		}
+	}
+	return file
+}
+
+// download fetches the URL into a new cache entry
+func (c *cache) download(URL string, headers http.Header, destFilename string, useContentDisposition bool) (*cacheEntry, error) {
+	request, err := http.NewRequest(http.MethodGet, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	addHeaders(request, headers)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Downloading %s failed. Status code %v", URL, response.StatusCode)
+	}
+
+	if useContentDisposition {
+		if _, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			destFilename = filepath.Base(params["filename"])
+		}
+	}
+
+	cacheDir, err := ioutil.TempDir(c.dir, "entry-")
+	if err != nil {
+		return nil, err
+	}
+	cacheDirName := filepath.Base(cacheDir)
+	file, err := os.Create(filepath.Join(cacheDir, destFilename))
+	if err != nil {
+		os.RemoveAll(cacheDir)
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), response.Body)
+	if err != nil {
+		os.RemoveAll(cacheDir)
+		return nil, err
+	}
+
+	return &cacheEntry{
+		Path:     filepath.Join(cacheDirName, destFilename),
+		Size:     size,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+		ETag:     response.Header.Get("ETag"),
+	}, nil
+}
+
+// open returns the cached file of the URL, downloading it with the given headers if it is not cached yet.
+// The cache is only locked to read and update its entries: entries are verified and downloaded without
+// holding the lock, one URL at a time.
+// The file is opened before being added to the cache, so that it stays readable even if the entry
+// is evicted while the file is in use.
+func (c *cache) open(URL string, headers http.Header, destFilename string, useContentDisposition bool) (*os.File, *cacheEntry, error) {
+	c.mux.Lock()
+	for {
+		done, inFlight := c.inFlight[URL]
+		if !inFlight {
+			break
+		}
+		c.mux.Unlock()
+		<-done
+		c.mux.Lock()
+	}
+	done := make(chan struct{})
+	c.inFlight[URL] = done
+	cached := c.entries[URL]
+	c.mux.Unlock()
+
+	entry := cached
+	file := c.openValid(URL, cached, headers)
+	var err error
+	if file == nil {
+		downloadCacheMisses.Inc()
+		entry, err = c.download(URL, headers, destFilename, useContentDisposition)
+		if err == nil {
+			file, err = os.Open(filepath.Join(c.dir, entry.Path))
+			if err != nil {
+				c.removeEntryDir(entry)
+			}
+		}
+	} else {
+		downloadCacheHits.Inc()
+		log.Info(join("",
+			"Retrieving URL '",
+			URL,
+			"' from the local cache:",
+			entry.Path))
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	delete(c.inFlight, URL)
+	close(done)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry != cached {
+		c.remove(URL)
+		c.entries[URL] = entry
+	}
+	// A cached entry may have been evicted while it was verified
+	if c.entries[URL] == entry {
+		entry.LastUsed = time.Now()
+		c.evict(URL)
+		c.saveIndex()
+	}
+	return file, entry, nil
+}
+
+func addHeaders(request *http.Request, headers http.Header) {
+	for name, values := range headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 type impl struct {
@@ -63,40 +448,15 @@ func NewCachingIoUtil() utils.IoUtil {
 }
 
 func (util *impl) Download(URL string, destPath string, useContentDisposition bool) (string, error) {
-	downloadCache.mux.Lock()
-	defer downloadCache.mux.Unlock()
-	path, exists := downloadCache.filenamesPerUrl[URL]
-	if !exists {
-		for {
-			cacheDirName := downloadCache.random.String(10)
-			cacheDir := filepath.Join(downloadCache.tempDir, cacheDirName)
-			os.MkdirAll(cacheDir, 755)
-			destDir, destFilename := filepath.Split(filepath.Clean(destPath))
-			path = filepath.Join(cacheDir, destFilename)
-			if _, err := os.Stat(path); err != nil {
-        if os.IsNotExist(err) {
-						path, err := util.delegate.Download(URL, path, useContentDisposition)
-						if err != nil {
-							return "", err
-						}
-						_, destFilename = filepath.Split(path)
-						destPath = filepath.Join(destDir, destFilename)
-						downloadCache.filenamesPerUrl[URL] = path
-            break
-        } else {
-					return "", err
-				}
-			}
-		}
-	} else {
-		log.Info(join ("",
-		"Retrieving URL '",
-		URL,
-		"' from the local cache:",
-		path))
+	destDir, destFilename := filepath.Split(filepath.Clean(destPath))
+	file, entry, err := downloadCache.open(URL, nil, destFilename, useContentDisposition)
+	if err != nil {
+		return "", err
 	}
+	defer file.Close()
 
-	return destPath, util.CopyFile(path, destPath)
+	destPath = filepath.Join(destDir, filepath.Base(entry.Path))
+	return destPath, util.CreateFile(destPath, file)
 }
 
 func (util *impl) MkDir(dir string) error {
@@ -104,6 +464,9 @@ func (util *impl) MkDir(dir string) error {
 }
 
 func (util *impl) Fetch(URL string) ([]byte, error) {
+	if isLocalRegistry(URL) {
+		return ioutil.ReadFile(strings.TrimPrefix(URL, localRegistryPrefix))
+	}
 	return util.delegate.Fetch(URL)
 }
 
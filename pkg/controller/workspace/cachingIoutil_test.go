@@ -0,0 +1,137 @@
+package workspace
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestCache(t *testing.T, maxSize int64) *cache {
+	dir, err := ioutil.TempDir("", "download-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cache{
+		dir:        dir,
+		maxSize:    maxSize,
+		persistent: true,
+		entries:    map[string]*cacheEntry{},
+		inFlight:   map[string]chan struct{}{},
+	}
+}
+
+func openCached(t *testing.T, c *cache, URL string) string {
+	file, _, err := c.open(URL, nil, "file", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestDownloadCacheEvictsLeastRecentlyUsedEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 6)))
+	}))
+	defer server.Close()
+	c := newTestCache(t, 12)
+	defer os.RemoveAll(c.dir)
+
+	openCached(t, c, server.URL+"/a")
+	openCached(t, c, server.URL+"/b")
+	openCached(t, c, server.URL+"/a")
+	evictedEntry := c.entries[server.URL+"/b"]
+	openCached(t, c, server.URL+"/c")
+
+	if len(c.entries) != 2 || c.entries[server.URL+"/a"] == nil || c.entries[server.URL+"/c"] == nil {
+		t.Errorf("Expected the least recently used entry to be evicted, got %v", c.entries)
+	}
+	if _, err := os.Stat(filepath.Join(c.dir, evictedEntry.Path)); !os.IsNotExist(err) {
+		t.Errorf("Expected the file of the evicted entry to be removed, got %v", err)
+	}
+}
+
+func TestDownloadCacheRevalidatesETag(t *testing.T) {
+	etag, downloads := `"v1"`, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Method == http.MethodGet {
+			downloads++
+		}
+		w.Write([]byte(etag))
+	}))
+	defer server.Close()
+	c := newTestCache(t, 1024)
+	defer os.RemoveAll(c.dir)
+
+	openCached(t, c, server.URL)
+	if content := openCached(t, c, server.URL); content != `"v1"` || downloads != 1 {
+		t.Errorf("Expected the unmodified file to be served from the cache, got %q after %d downloads", content, downloads)
+	}
+	etag = `"v2"`
+	if content := openCached(t, c, server.URL); content != `"v2"` || downloads != 2 {
+		t.Errorf("Expected the modified file to be downloaded again, got %q after %d downloads", content, downloads)
+	}
+}
+
+func TestDownloadCacheReloadsIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+	c := newTestCache(t, 1024)
+	defer os.RemoveAll(c.dir)
+	openCached(t, c, server.URL)
+
+	outsideDir, err := ioutil.TempDir("", "download-cache-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outsideDir)
+	outsideFile := filepath.Join(outsideDir, "file")
+	if err := ioutil.WriteFile(outsideFile, []byte("outside"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	relativeOutsideFile, err := filepath.Rel(c.dir, outsideFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.entries["http://outside"] = &cacheEntry{Path: relativeOutsideFile, Checksum: "invalid"}
+	c.entries["http://flat"] = &cacheEntry{Path: "file", Checksum: "invalid"}
+	c.saveIndex()
+	if err := os.Mkdir(filepath.Join(c.dir, "entry-orphan"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := newTestCache(t, 1024)
+	os.RemoveAll(reloaded.dir)
+	reloaded.dir = c.dir
+	reloaded.loadIndex()
+
+	if len(reloaded.entries) != 1 || reloaded.entries[server.URL] == nil {
+		entries, _ := json.Marshal(reloaded.entries)
+		t.Errorf("Expected only the valid entry to be reloaded, got %s", entries)
+	}
+	if _, err := os.Stat(outsideFile); err != nil {
+		t.Errorf("Expected the files outside of the cache directory to be kept, got %v", err)
+	}
+	if _, err := os.Stat(c.dir); err != nil {
+		t.Errorf("Expected the cache directory to be kept, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(c.dir, "entry-orphan")); !os.IsNotExist(err) {
+		t.Errorf("Expected the entry directories missing from the index to be removed, got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package workspace
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -9,9 +11,16 @@ import (
 	k8sModelUtils "github.com/che-incubator/che-workspace-crd-operator/pkg/controller/modelutils/k8s"
 )
 
+// Name of the container that serves the Che REST APIs to the workspace tooling
+const cheRestApisContainerName = "che-rest-apis"
+
 func addCheRestApis(wkspProps workspaceProperties, podSpec *corev1.PodSpec) ([]runtime.Object, string, error) {
+	// che-rest-apis reads the Workspace custom resource with the ServiceAccount token
+	if controllerConfig.getRbacProfile() == rbacProfileNone {
+		return nil, "", fmt.Errorf("The '%s' RBAC profile doesn't allow the '%s' container to get the workspace: use the '%s' or '%s' profile", rbacProfileNone, cheRestApisContainerName, rbacProfileMinimal, rbacProfileFull)
+	}
 	cheRestApisPort := 9999
-	containerName := "che-rest-apis"
+	containerName := cheRestApisContainerName
 	podSpec.Containers = append(podSpec.Containers, corev1.Container{
 		Image:           controllerConfig.getCheRestApisDockerImage(),
 		ImagePullPolicy: corev1.PullIfNotPresent,
@@ -38,6 +47,9 @@ func addCheRestApis(wkspProps workspaceProperties, podSpec *corev1.PodSpec) ([]r
 		},
 		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 	})
+	if controllerConfig.getRbacProfile() == rbacProfileMinimal {
+		addCheRestApisToken(podSpec, &podSpec.Containers[len(podSpec.Containers)-1])
+	}
 
 	serviceName, servicePort := containerName, k8sModelUtils.ServicePortName(cheRestApisPort)
 	serviceNameAndPort := join("-", serviceName, servicePort)
@@ -107,3 +119,55 @@ func addCheRestApis(wkspProps workspaceProperties, podSpec *corev1.PodSpec) ([]r
 
 	return []runtime.Object{&service, &ingress}, ingressUrl, nil
 }
+
+// addCheRestApisToken mounts the ServiceAccount token to the che-rest-apis container only,
+// at the location where Kubernetes clients look for it, through a projected volume.
+// The CA certificate is taken from the `kube-root-ca.crt` ConfigMap when the cluster publishes it.
+// The kubelet rotates the token before it expires, and che-rest-apis re-reads it on each request.
+func addCheRestApisToken(podSpec *corev1.PodSpec, container *corev1.Container) {
+	volumeName := cheRestApisContainerName + "-token"
+	tokenExpirationSeconds := int64(3600)
+	optional := true
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					corev1.VolumeProjection{
+						ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+							Path:              "token",
+							ExpirationSeconds: &tokenExpirationSeconds,
+						},
+					},
+					corev1.VolumeProjection{
+						ConfigMap: &corev1.ConfigMapProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "kube-root-ca.crt"},
+							Items: []corev1.KeyToPath{
+								corev1.KeyToPath{Key: "ca.crt", Path: "ca.crt"},
+							},
+							Optional: &optional,
+						},
+					},
+					corev1.VolumeProjection{
+						DownwardAPI: &corev1.DownwardAPIProjection{
+							Items: []corev1.DownwardAPIVolumeFile{
+								corev1.DownwardAPIVolumeFile{
+									Path: "namespace",
+									FieldRef: &corev1.ObjectFieldSelector{
+										APIVersion: "v1",
+										FieldPath:  "metadata.namespace",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      volumeName,
+		MountPath: "/var/run/secrets/kubernetes.io/serviceaccount",
+		ReadOnly:  true,
+	})
+}
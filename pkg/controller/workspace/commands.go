@@ -0,0 +1,115 @@
+package workspace
+
+import (
+	"strconv"
+	"strings"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+)
+
+// contributeDevfileCommands converts the devfile commands into runtime commands, contributed by the components
+// their actions point to.
+//
+// A command with several actions is converted into one command per action, named `{command}-{index}`,
+// along with a `composite` command that runs them in sequence, contributed by the component of the first action.
+// Actions that don't point to any component, such as `vscode-task` and `vscode-launch` actions,
+// are contributed by the editor.
+func contributeDevfileCommands(names workspaceProperties, commands []workspaceApi.CommandSpec, statuses []ComponentInstanceStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+
+	defaultComponent := 0
+	componentsByAlias := map[string]int{}
+	for index, status := range statuses {
+		if status.Alias != "" {
+			componentsByAlias[status.Alias] = index
+		}
+		if status.ComponentType == workspaceApi.CheEditor {
+			defaultComponent = index
+		}
+	}
+
+	for _, command := range commands {
+		if len(command.Actions) == 0 {
+			continue
+		}
+
+		actionCommandNames := []string{}
+		compositeComponent := -1
+		for actionIndex, action := range command.Actions {
+			componentIndex := defaultComponent
+			if action.Component != nil {
+				index, exists := componentsByAlias[*action.Component]
+				if !exists {
+					log.Info("Ignoring action of command '" + command.Name + "' that points to unknown component '" + *action.Component + "'")
+					continue
+				}
+				componentIndex = index
+			}
+			if compositeComponent < 0 {
+				compositeComponent = componentIndex
+			}
+
+			runtimeCommand := actionRuntimeCommand(names, command, action, &statuses[componentIndex])
+			if len(command.Actions) > 1 {
+				runtimeCommand.Name = command.Name + "-" + strconv.Itoa(actionIndex+1)
+				runtimeCommand.Attributes[COMPOSITE_COMMAND_ATTRIBUTE] = command.Name
+				actionCommandNames = append(actionCommandNames, runtimeCommand.Name)
+			}
+			statuses[componentIndex].ContributedRuntimeCommands = append(statuses[componentIndex].ContributedRuntimeCommands, runtimeCommand)
+		}
+
+		if len(actionCommandNames) > 0 {
+			attributes := map[string]string{}
+			for attrName, attrValue := range command.Attributes {
+				attributes[attrName] = attrValue
+			}
+			statuses[compositeComponent].ContributedRuntimeCommands = append(statuses[compositeComponent].ContributedRuntimeCommands,
+				CheWorkspaceCommand{
+					Name:        command.Name,
+					CommandLine: strings.Join(actionCommandNames, ";"),
+					Type:        COMPOSITE_COMMAND_TYPE,
+					Attributes:  attributes,
+				})
+		}
+	}
+}
+
+// actionRuntimeCommand converts a single command action into the runtime command of the component it points to
+func actionRuntimeCommand(names workspaceProperties, command workspaceApi.CommandSpec, action workspaceApi.CommandActionSpec, status *ComponentInstanceStatus) CheWorkspaceCommand {
+	attributes := map[string]string{
+		COMMAND_WORKING_DIRECTORY_ATTRIBUTE:        interpolate(emptyIfNil(action.Workdir), names),
+		COMMAND_ACTION_REFERENCE_ATTRIBUTE:         emptyIfNil(action.Reference),
+		COMMAND_ACTION_REFERENCE_CONTENT_ATTRIBUTE: emptyIfNil(action.ReferenceContent),
+	}
+	if action.Component != nil {
+		attributes[COMPONENT_ALIAS_COMMAND_ATTRIBUTE] = *action.Component
+
+		// Plugins with several containers run the command in their first one,
+		// and other components with several machines let the user choose.
+		if pluginId := status.ResolvedPluginId; pluginId != "" {
+			attributes[COMMAND_PLUGIN_ATTRIBUTE] = pluginId
+		} else if status.PluginId != "" {
+			attributes[COMMAND_PLUGIN_ATTRIBUTE] = status.PluginId
+		}
+		if len(status.Machines) == 1 {
+			for machineName := range status.Machines {
+				attributes[COMMAND_MACHINE_NAME_ATTRIBUTE] = machineName
+			}
+		} else if attributes[COMMAND_PLUGIN_ATTRIBUTE] != "" && status.WorkspacePodAdditions != nil &&
+			len(status.WorkspacePodAdditions.Spec.Containers) > 0 {
+			attributes[COMMAND_MACHINE_NAME_ATTRIBUTE] = status.WorkspacePodAdditions.Spec.Containers[0].Name
+		}
+	}
+	for attrName, attrValue := range command.Attributes {
+		attributes[attrName] = attrValue
+	}
+
+	return CheWorkspaceCommand{
+		Name:        command.Name,
+		CommandLine: emptyIfNil(action.Command),
+		Type:        action.Type,
+		Attributes:  attributes,
+	}
+}
@@ -2,7 +2,6 @@ package workspace
 
 import (
 	workspacev1alpha1 "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
-	"github.com/eclipse/che-plugin-broker/model"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,11 +24,30 @@ type MachineDescription struct {
 	Ports                           []int              `json:"ports,omitempty"`
 }
 
+// Describes an extension archive of a Theia or VS Code plugin, to download into the plugins volume
+type ExtensionDownload struct {
+	URL            string
+	DestinationDir string
+	FileName       string
+	// SHA-256 checksum of the extension published in the plugin meta.yaml, if any
+	Checksum       string
+}
+
 type ComponentInstanceStatus struct {
+	// Alias of the component in the devfile, if any
+	Alias                           string                         `json:"alias,omitempty"`
+	// Type of the component in the devfile
+	ComponentType                   workspacev1alpha1.DevfileName  `json:"componentType,omitempty"`
+	// `automountWorkspaceSecrets` value of the component, if set
+	AutomountWorkspaceSecrets       *bool                          `json:"-"`
 	Machines                        map[string]MachineDescription  `json:"machines,omitempty"`
 	ContributedRuntimeCommands      []CheWorkspaceCommand          `json:"contributedRuntimeCommands,omitempty"`
 	WorkspacePodAdditions           *corev1.PodTemplateSpec        `json:"-"`
 	ExternalObjects                 []runtime.Object               `json:"-"`
-	PluginFQN                       *model.PluginFQN               `json:"-"`
+	Extensions                      []ExtensionDownload            `json:"-"`
+	// Plugin id of the component as written in the devfile
+	PluginId                        string                         `json:"pluginId,omitempty"`
+	// Plugin id with the concrete version the component id was resolved to
+	ResolvedPluginId                string                         `json:"resolvedPluginId,omitempty"`
 	Endpoints                       []workspacev1alpha1.Endpoint   `json:"-"`
 }
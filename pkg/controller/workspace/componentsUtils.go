@@ -39,7 +39,71 @@ func createVolumeMounts(workspaceProps workspaceProperties, mountSources *bool,
 	return volumeMounts
 }
 
+// mountSourcesOrDefault returns whether the projects sources should be mounted to the containers of the component.
+// As in the devfile 1.0 format, sources are mounted by default to the containers of kubernetes and openshift components,
+// but not to dockerimage containers. Kubernetes and openshift components of workspaces whose volumes are not persisted
+// are an exception, since their pods can't share the ephemeral workspace volume.
+func mountSourcesOrDefault(component *workspaceApi.ComponentSpec) *bool {
+	if component.MountSources != nil {
+		return component.MountSources
+	}
+	mountSources := component.Type == workspaceApi.Kubernetes || component.Type == workspaceApi.Openshift
+	return &mountSources
+}
+
+// componentEnvVars returns the environment variables of the component.
+// Variables with a `valueFrom` take their value from a key of a Secret or ConfigMap of the workspace namespace.
+func componentEnvVars(component *workspaceApi.ComponentSpec) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{}
+	for _, envVarDef := range component.Env {
+		envVar := corev1.EnvVar{
+			Name:  envVarDef.Name,
+			Value: strings.ReplaceAll(envVarDef.Value, "$(CHE_PROJECTS_ROOT)", "/projects"),
+		}
+		if valueFrom := envVarDef.ValueFrom; valueFrom != nil {
+			envVar.ValueFrom = &corev1.EnvVarSource{}
+			if valueFrom.SecretKeyRef != nil {
+				envVar.ValueFrom.SecretKeyRef = &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: valueFrom.SecretKeyRef.Name},
+					Key:                  valueFrom.SecretKeyRef.Key,
+				}
+			}
+			if valueFrom.ConfigMapKeyRef != nil {
+				envVar.ValueFrom.ConfigMapKeyRef = &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: valueFrom.ConfigMapKeyRef.Name},
+					Key:                  valueFrom.ConfigMapKeyRef.Key,
+				}
+			}
+		}
+		envVars = append(envVars, envVar)
+	}
+	return envVars
+}
+
+// componentEnvFrom returns the Secrets whose keys are all set as environment variables of the component containers
+func componentEnvFrom(component *workspaceApi.ComponentSpec) []corev1.EnvFromSource {
+	var envFrom []corev1.EnvFromSource
+	for _, envFromDef := range component.EnvFrom {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			Prefix: envFromDef.Prefix,
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: envFromDef.SecretName},
+			},
+		})
+	}
+	return envFrom
+}
+
 func createK8sServicesForMachines(wkspProps workspaceProperties, machineName string, exposedPorts []int) []corev1.Service {
+	return createK8sServicesForPods(wkspProps, machineName, exposedPorts, map[string]string{
+		"che.original_name":   cheOriginalName,
+		"che.workspace_id":    wkspProps.workspaceId,
+		DEPLOYMENT_NAME_LABEL: wkspProps.workspaceId + "." + cheOriginalName,
+	})
+}
+
+// createK8sServicesForPods creates the services of a machine that runs in the pods matching the given selector
+func createK8sServicesForPods(wkspProps workspaceProperties, machineName string, exposedPorts []int, podSelector map[string]string) []corev1.Service {
 	services := []corev1.Service {}
 	servicePorts := k8sModelUtils.BuildServicePorts(exposedPorts, corev1.ProtocolTCP)
 	serviceName := machineServiceName(wkspProps, machineName)
@@ -56,10 +120,7 @@ func createK8sServicesForMachines(wkspProps workspaceProperties, machineName str
 				},
 			},
 			Spec: corev1.ServiceSpec{
-				Selector: map[string]string{
-					"che.original_name": cheOriginalName,
-					"che.workspace_id":  wkspProps.workspaceId,
-				},
+				Selector: podSelector,
 				Type:  corev1.ServiceTypeClusterIP,
 				Ports: servicePorts,
 			},
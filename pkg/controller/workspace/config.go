@@ -4,16 +4,23 @@ package workspace
 
 import (
 	"github.com/che-incubator/che-workspace-crd-operator/pkg/controller/registry"
+	"strconv"
 	"strings"
 	"context"
 	"errors"
+	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	routeV1 "github.com/openshift/api/route/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -34,6 +41,10 @@ var (
 type ControllerConfig struct {
 	configMap             *corev1.ConfigMap
 	controllerIsOpenshift bool
+	// HTTP headers to send to private plugin registries, by registry host.
+	// They are updated from the watch of the Secret and read from the reconciles, hence the lock.
+	pluginRegistryHeaders    map[string]http.Header
+	pluginRegistryHeadersMux sync.RWMutex
 }
 
 func (wc *ControllerConfig) update(configMap *corev1.ConfigMap) {
@@ -41,12 +52,81 @@ func (wc *ControllerConfig) update(configMap *corev1.ConfigMap) {
 	wc.configMap = configMap
 }
 
+func (wc *ControllerConfig) updatePluginRegistryAuth(secret *corev1.Secret) {
+	wc.pluginRegistryHeadersMux.Lock()
+	defer wc.pluginRegistryHeadersMux.Unlock()
+	if secret == nil {
+		wc.pluginRegistryHeaders = nil
+		return
+	}
+	log.Info(join("", "Updating the plugin registry credentials from secret '", secret.Name, "' in namespace '", secret.Namespace, "'"))
+	headers := map[string]http.Header{}
+	for host, content := range secret.Data {
+		headers[host] = parseRegistryHeaders(string(content))
+	}
+	wc.pluginRegistryHeaders = headers
+}
+
+// getPluginRegistry returns the default plugin registry, which is the first one of the configured registries
 func (wc *ControllerConfig) getPluginRegistry() string {
+	registries := wc.getPluginRegistries()
+	if len(registries) > 0 {
+		return registries[0]
+	}
+	return ""
+}
+
+// getPluginRegistries returns the ordered list of plugin registries
+// set as a comma-separated list in the `plugin.registry` property.
+func (wc *ControllerConfig) getPluginRegistries() []string {
 	optional := wc.getProperty("plugin.registry")
-	if optional != nil {
-		return *optional
+	if optional == nil {
+		if registry.EmbeddedPluginRegistryUrl == "" {
+			return []string{}
+		}
+		return []string{registry.EmbeddedPluginRegistryUrl}
+	}
+	registries := []string{}
+	for _, registryUrl := range strings.Split(*optional, ",") {
+		registryUrl = strings.TrimSpace(registryUrl)
+		if registryUrl != "" {
+			registries = append(registries, registryUrl)
+		}
 	}
-	return registry.EmbeddedPluginRegistryUrl
+	return registries
+}
+
+// getPluginRegistryAuthSecret returns the name of the secret, in the namespace of the controller config map,
+// that contains the headers to send to private plugin registries.
+// Each key of the secret is a registry host, and each value contains header lines in the `Name: value` format.
+func (wc *ControllerConfig) getPluginRegistryAuthSecret() string {
+	optional := wc.getProperty("plugin.registry.auth.secret")
+	if optional == nil {
+		return ""
+	}
+	return *optional
+}
+
+func (wc *ControllerConfig) getPluginRegistryHeaders(registryUrl string) http.Header {
+	wc.pluginRegistryHeadersMux.RLock()
+	defer wc.pluginRegistryHeadersMux.RUnlock()
+	if wc.pluginRegistryHeaders == nil {
+		return nil
+	}
+	return wc.pluginRegistryHeaders[registryHost(registryUrl)]
+}
+
+func (wc *ControllerConfig) getPluginRegistryCacheTTL() time.Duration {
+	optional := wc.getProperty("plugin.registry.cache.ttl")
+	if optional == nil {
+		return defaultPluginRegistryCacheTTL
+	}
+	ttl, err := time.ParseDuration(*optional)
+	if err != nil {
+		log.Error(err, "Invalid plugin registry cache TTL: "+*optional)
+		return defaultPluginRegistryCacheTTL
+	}
+	return ttl
 }
 
 func (wc *ControllerConfig) getIngressGlobalDomain() string {
@@ -65,6 +145,92 @@ func (wc *ControllerConfig) getCheRestApisDockerImage() string {
 	return *optional
 }
 
+// getDefaultEditor returns the id of the editor plugin added to workspaces whose devfile has no `cheEditor` component.
+// An empty value disables the injection of the default editor.
+func (wc *ControllerConfig) getDefaultEditor() string {
+	optional := wc.getProperty("che.default.editor")
+	if optional == nil {
+		return defaultEditor
+	}
+	return strings.TrimSpace(*optional)
+}
+
+// getDefaultPlugins returns the ids of the plugins added along with the default editor,
+// set as a comma-separated list in the `che.default.plugins` property.
+func (wc *ControllerConfig) getDefaultPlugins() []string {
+	value := defaultPlugins
+	if optional := wc.getProperty("che.default.plugins"); optional != nil {
+		value = *optional
+	}
+	plugins := []string{}
+	for _, plugin := range strings.Split(value, ",") {
+		plugin = strings.TrimSpace(plugin)
+		if plugin != "" {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
+
+func (wc *ControllerConfig) getPluginsCopyImage() string {
+	optional := wc.getProperty("plugins.copy.image.name")
+	if optional == nil {
+		return "registry.access.redhat.com/ubi8/ubi-minimal"
+	}
+	return *optional
+}
+
+// getPluginsCacheMaxSize returns the maximum size, in bytes, of the extension cache shared by the workspaces
+// of a namespace. A size of 0 disables the cache.
+func (wc *ControllerConfig) getPluginsCacheMaxSize() int64 {
+	maxSize := defaultPluginsCacheMaxSize
+	optional := wc.getProperty("plugins.cache.max.size")
+	if optional != nil {
+		maxSize = *optional
+	}
+	quantity, err := resource.ParseQuantity(maxSize)
+	if err != nil {
+		log.Error(err, "Invalid plugins cache maximum size: "+maxSize)
+		return 0
+	}
+	return quantity.Value()
+}
+
+// getRecipeAllowedKinds returns the kinds of objects that kubernetes and openshift components may create,
+// set as a comma-separated list of `{apiVersion}/{kind}` in the `recipe.allowed.kinds` property.
+// Pods are always allowed, since they are converted to workspace deployments.
+func (wc *ControllerConfig) getRecipeAllowedKinds() []schema.GroupVersionKind {
+	value := defaultRecipeAllowedKinds
+	if optional := wc.getProperty("recipe.allowed.kinds"); optional != nil {
+		value = *optional
+	}
+	kinds := []schema.GroupVersionKind{}
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		separator := strings.LastIndex(kind, "/")
+		if separator <= 0 {
+			if kind != "" {
+				log.Info("Ignoring invalid recipe allowed kind: " + kind)
+			}
+			continue
+		}
+		kinds = append(kinds, schema.FromAPIVersionAndKind(kind[:separator], kind[separator+1:]))
+	}
+	return kinds
+}
+
+func (wc *ControllerConfig) isRecipeKindAllowed(gvk schema.GroupVersionKind) bool {
+	if gvk.Group == "" && gvk.Kind == "Pod" {
+		return true
+	}
+	for _, allowed := range wc.getRecipeAllowedKinds() {
+		if allowed.GroupKind() == gvk.GroupKind() {
+			return true
+		}
+	}
+	return false
+}
+
 func (wc *ControllerConfig) isOpenshift() bool {
 	return wc.controllerIsOpenshift
 }
@@ -77,6 +243,137 @@ func (wc *ControllerConfig) getSidecarPullPolicy() string {
 	return *optional
 }
 
+// getImagePullSecrets returns the names of the Secrets used to pull the images of all the workspaces,
+// set as a comma-separated list in the `image.pull.secrets` property.
+// The Secrets are expected to exist in the namespace of each workspace.
+func (wc *ControllerConfig) getImagePullSecrets() []string {
+	optional := wc.getProperty("image.pull.secrets")
+	if optional == nil {
+		return []string{}
+	}
+	pullSecrets := []string{}
+	for _, pullSecret := range strings.Split(*optional, ",") {
+		pullSecret = strings.TrimSpace(pullSecret)
+		if pullSecret != "" {
+			pullSecrets = append(pullSecrets, pullSecret)
+		}
+	}
+	return pullSecrets
+}
+
+// getDevfileSourceAllowedHosts returns the hosts from which devfiles and devfile parents can be fetched
+// even though they resolve to loopback, link-local or private addresses,
+// set as a comma-separated list in the `devfile.source.allowed.hosts` property.
+func (wc *ControllerConfig) getDevfileSourceAllowedHosts() []string {
+	optional := wc.getProperty("devfile.source.allowed.hosts")
+	if optional == nil {
+		return []string{}
+	}
+	hosts := []string{}
+	for _, host := range strings.Split(*optional, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// isSSHKeysGenerationEnabled returns whether an SSH keypair is generated for the workspace users
+// that don't have a `{user}-ssh-keys` Secret yet, unless the `git.ssh.keys.generate` property is `false`
+func (wc *ControllerConfig) isSSHKeysGenerationEnabled() bool {
+	optional := wc.getProperty("git.ssh.keys.generate")
+	return optional == nil || strings.TrimSpace(*optional) != "false"
+}
+
+// getSecurityProfile returns the security settings of the workspace pods, set in the `security.*` properties.
+// By default the security settings of the pods and of the images are kept. Setting the `security.profile`
+// property to `restricted` makes the containers run as a non-root user, with all their capabilities dropped.
+// An empty property disables the corresponding setting.
+// On OpenShift, the user and the filesystem group are left to the security context constraints,
+// which assign arbitrary ids to the workspace pods.
+func (wc *ControllerConfig) getSecurityProfile() securityProfile {
+	profile := securityProfile{}
+	defaultUserId := ""
+	if optional := wc.getProperty("security.profile"); optional != nil && strings.TrimSpace(*optional) == "restricted" {
+		profile.runAsNonRoot = true
+		profile.dropCapabilities = []corev1.Capability{"ALL"}
+		if !wc.isOpenshift() {
+			defaultUserId = defaultSecurityUserId
+		}
+	}
+	profile.runAsUser = wc.getInt64Property("security.run.as.user", defaultUserId)
+	profile.fsGroup = wc.getInt64Property("security.fs.group", defaultUserId)
+	if optional := wc.getProperty("security.run.as.non.root"); optional != nil {
+		profile.runAsNonRoot = strings.TrimSpace(*optional) == "true"
+	}
+	if optional := wc.getProperty("security.capabilities.drop"); optional != nil {
+		profile.dropCapabilities = []corev1.Capability{}
+		for _, capability := range strings.Split(*optional, ",") {
+			capability = strings.TrimSpace(capability)
+			if capability != "" {
+				profile.dropCapabilities = append(profile.dropCapabilities, corev1.Capability(capability))
+			}
+		}
+	}
+	if optional := wc.getProperty("security.sidecars.read.only.root.filesystem"); optional != nil {
+		profile.readOnlySidecars = strings.TrimSpace(*optional) == "true"
+	}
+	if optional := wc.getProperty("security.seccomp.profile"); optional != nil {
+		profile.seccompProfile = strings.TrimSpace(*optional)
+	}
+	return profile
+}
+
+// RBAC profiles of the workspace ServiceAccount
+const (
+	// No permission. Workspaces fail to start, since che-rest-apis can't get the workspace
+	rbacProfileNone = "none"
+	// Only the permission to get the workspaces, needed by che-rest-apis
+	rbacProfileMinimal = "minimal"
+	// View of the namespace, exec in its pods, and view of its workspaces
+	rbacProfileFull = "full"
+)
+
+// getRbacProfile returns the permissions granted to the workspace ServiceAccount, set in the `rbac.profile` property:
+// `none`, `minimal` or `full`, which is the default.
+func (wc *ControllerConfig) getRbacProfile() string {
+	optional := wc.getProperty("rbac.profile")
+	if optional == nil {
+		return rbacProfileFull
+	}
+	switch profile := strings.TrimSpace(*optional); profile {
+	case rbacProfileNone, rbacProfileMinimal, rbacProfileFull:
+		return profile
+	default:
+		log.Info("Unknown RBAC profile '" + profile + "', using the '" + rbacProfileFull + "' profile")
+		return rbacProfileFull
+	}
+}
+
+// isServiceAccountPerWorkspace returns whether each workspace gets its own ServiceAccount,
+// when the `rbac.service.account.per.workspace` property is "true"
+func (wc *ControllerConfig) isServiceAccountPerWorkspace() bool {
+	optional := wc.getProperty("rbac.service.account.per.workspace")
+	return optional != nil && strings.TrimSpace(*optional) == "true"
+}
+
+func (wc *ControllerConfig) getInt64Property(name string, defaultValue string) *int64 {
+	value := defaultValue
+	if optional := wc.getProperty(name); optional != nil {
+		value = strings.TrimSpace(*optional)
+	}
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Error(err, "Invalid value of the '"+name+"' property: "+value)
+		return nil
+	}
+	return &parsed
+}
+
 func (wc *ControllerConfig) getProperty(name string) *string {
 	val, exists := wc.configMap.Data[name]
 	if exists {
@@ -85,9 +382,12 @@ func (wc *ControllerConfig) getProperty(name string) *string {
 	return nil
 }
 
+func isControllerConfigMap(meta metav1.Object) bool {
+	return meta.GetNamespace() == configMapReference.Namespace && meta.GetName() == configMapReference.Name
+}
+
 func updateConfigMap(client client.Client, meta metav1.Object, obj runtime.Object) {
-	if meta.GetNamespace() != configMapReference.Namespace ||
-		meta.GetName() != configMapReference.Name {
+	if !isControllerConfigMap(meta) {
 		return
 	}
 	if cm, isConfigMap := obj.(*corev1.ConfigMap); isConfigMap {
@@ -103,6 +403,32 @@ func updateConfigMap(client client.Client, meta metav1.Object, obj runtime.Objec
 	controllerConfig.update(configMap)
 }
 
+func updatePluginRegistryAuthSecret(client client.Client, meta metav1.Object, obj runtime.Object) {
+	secretName := controllerConfig.getPluginRegistryAuthSecret()
+	if secretName == "" {
+		controllerConfig.updatePluginRegistryAuth(nil)
+		return
+	}
+	if meta != nil &&
+		(meta.GetNamespace() != configMapReference.Namespace ||
+			meta.GetName() != secretName) {
+		return
+	}
+	if secret, isSecret := obj.(*corev1.Secret); isSecret {
+		controllerConfig.updatePluginRegistryAuth(secret)
+		return
+	}
+
+	secret := &corev1.Secret{}
+	err := client.Get(context.TODO(), types.NamespacedName{Name: secretName, Namespace: configMapReference.Namespace}, secret)
+	if err != nil {
+		log.Error(err, join("", "Cannot find the '", secretName, "' plugin registry secret in namespace '", configMapReference.Namespace, "'"))
+		controllerConfig.updatePluginRegistryAuth(nil)
+		return
+	}
+	controllerConfig.updatePluginRegistryAuth(secret)
+}
+
 func watchControllerConfig(ctr controller.Controller, mgr manager.Manager) error {
 	customConfig := false
 	configMapName, found := os.LookupEnv(ConfigMapNameEnvVar)
@@ -152,6 +478,7 @@ func watchControllerConfig(ctr controller.Controller, mgr manager.Manager) error
 	}
 
 	updateConfigMap(nonCachedClient, configMap.GetObjectMeta(), configMap)
+	updatePluginRegistryAuthSecret(nonCachedClient, nil, nil)
 
 	var emptyMapper handler.ToRequestsFunc = func(obj handler.MapObject) []reconcile.Request {
 		return []reconcile.Request{}
@@ -160,7 +487,10 @@ func watchControllerConfig(ctr controller.Controller, mgr manager.Manager) error
 		ToRequests: emptyMapper,
 	}, predicate.Funcs{
 		UpdateFunc: func(evt event.UpdateEvent) bool {
-			updateConfigMap(mgr.GetClient(), evt.MetaNew, evt.ObjectNew)
+			if isControllerConfigMap(evt.MetaNew) {
+				updateConfigMap(mgr.GetClient(), evt.MetaNew, evt.ObjectNew)
+				updatePluginRegistryAuthSecret(mgr.GetClient(), nil, nil)
+			}
 			return false
 		},
 		CreateFunc: func(evt event.CreateEvent) bool {
@@ -174,6 +504,32 @@ func watchControllerConfig(ctr controller.Controller, mgr manager.Manager) error
 			return false
 		},
 	})
+	if err != nil {
+		return err
+	}
+
+	err = ctr.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: emptyMapper,
+	}, predicate.Funcs{
+		UpdateFunc: func(evt event.UpdateEvent) bool {
+			updatePluginRegistryAuthSecret(mgr.GetClient(), evt.MetaNew, evt.ObjectNew)
+			return false
+		},
+		CreateFunc: func(evt event.CreateEvent) bool {
+			updatePluginRegistryAuthSecret(mgr.GetClient(), evt.Meta, evt.Object)
+			return false
+		},
+		DeleteFunc: func(evt event.DeleteEvent) bool {
+			if evt.Meta.GetNamespace() == configMapReference.Namespace &&
+				evt.Meta.GetName() == controllerConfig.getPluginRegistryAuthSecret() {
+				controllerConfig.updatePluginRegistryAuth(nil)
+			}
+			return false
+		},
+		GenericFunc: func(evt event.GenericEvent) bool {
+			return false
+		},
+	})
 
 	return err
 }
@@ -182,9 +538,7 @@ func buildDefaultConfigMap(cm *corev1.ConfigMap) {
 	cm.Name = configMapReference.Name
 	cm.Namespace = configMapReference.Namespace
 	cm.Data = map[string]string{
-		"ingress.global.domain":                      "",
-		"che.workspace.plugin_broker.unified.image":  "eclipse/che-unified-plugin-broker:v0.20",
-		"che.workspace.plugin_broker.init.image":     "eclipse/che-init-plugin-broker:v0.20",
+		"ingress.global.domain": "",
 	}
 }
 
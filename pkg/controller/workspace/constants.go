@@ -48,5 +48,64 @@ const (
 	COMPONENT_ALIAS_COMMAND_ATTRIBUTE = "componentAlias"
 
 	DEPLOYMENT_NAME_LABEL = "deployment"
-)
 
+	// Workspace annotation that disables the injection of the default editor and plugins
+	// when set to "true"
+	SKIP_DEFAULT_EDITOR_ANNOTATION = "org.eclipse.che.workspace/skip-default-editor"
+
+	// Workspace annotation that makes the controller store the objects it would create for the workspace
+	// in the `{workspace}-rendered` ConfigMap, when set to "true". The objects of a stopped workspace are only
+	// rendered, while a started workspace is still reconciled.
+	RENDER_ANNOTATION = "org.eclipse.che.workspace/render"
+
+	// Workspace annotation whose value changes make the controller fetch the devfile of the `devfileSource` again
+	REFRESH_DEVFILE_ANNOTATION = "org.eclipse.che.workspace/refresh-devfile"
+
+	// Workspace status additional info that contain the devfile resolved from the `devfileSource`,
+	// along with the source and the value of the refresh annotation it was resolved for
+	DEVFILE_ADDITIONAL_INFO         = "org.eclipse.che.workspace/devfile"
+	DEVFILE_SOURCE_ADDITIONAL_INFO  = "org.eclipse.che.workspace/devfile-source"
+	DEVFILE_REFRESH_ADDITIONAL_INFO = "org.eclipse.che.workspace/devfile-refresh"
+
+	// Attribute of the commands generated from the actions of a multi-action devfile command,
+	// which contains the name of the composite command they belong to
+	COMPOSITE_COMMAND_ATTRIBUTE = "compositeCommand"
+
+	// Type of the command that runs the commands generated from each action of a multi-action devfile command
+	COMPOSITE_COMMAND_TYPE = "composite"
+
+	// Label of the Deployments and StatefulSets of kubernetes components, which are scaled to zero
+	// instead of being deleted when the workspace is stopped
+	SCALE_TO_ZERO_ON_STOP_LABEL = "che.workspace_scale_to_zero_on_stop"
+
+	// Annotation of the Deployments and StatefulSets scaled to zero when the workspace is stopped,
+	// that contains their number of replicas before the workspace was stopped
+	REPLICAS_BEFORE_STOP_ANNOTATION = "che.workspace_replicas_before_stop"
+
+	// Workspace annotation that contains the user the workspace belongs to, whose `{user}-ssh-keys`
+	// and `{user}-user-profile` Secrets are mounted to the workspace containers
+	USER_ANNOTATION = "org.eclipse.che.workspace/user"
+
+	// Label of the Secrets and ConfigMaps of the workspace namespace that are mounted to the workspace containers,
+	// when set to "true"
+	AUTOMOUNT_LABEL = "che.eclipse.org/mount-to-workspace"
+
+	// Annotation of the labelled Secrets and ConfigMaps that tells how they are mounted:
+	// "file" (the default), "env" or "git-credentials"
+	AUTOMOUNT_AS_ANNOTATION = "che.eclipse.org/mount-as"
+
+	// Annotation of the labelled Secrets and ConfigMaps mounted as files that contains their mount path
+	AUTOMOUNT_PATH_ANNOTATION = "che.eclipse.org/mount-path"
+
+	// Annotation of the labelled Secrets and ConfigMaps that restricts them to the components
+	// that set `automountWorkspaceSecrets` to true, when set to "false"
+	AUTOMOUNT_WORKSPACE_SECRET_ANNOTATION = "che.eclipse.org/automount-workspace-secret"
+
+	// Pod template annotation that contains the hash of the versions of the mounted Secrets and ConfigMaps,
+	// so that changing them rolls out the workspace pods
+	AUTOMOUNT_HASH_ANNOTATION = "che.eclipse.org/automount-hash"
+
+	// Annotation of the workspace ServiceAccount that contains the comma-separated names of the pull secrets
+	// added by the controller, so that the ones removed from the configuration are removed from the ServiceAccount
+	IMAGE_PULL_SECRETS_ANNOTATION = "org.eclipse.che.workspace/image-pull-secrets"
+)
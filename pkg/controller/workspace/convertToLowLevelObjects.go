@@ -1,11 +1,12 @@
 package workspace
 
 import (
+	"encoding/json"
 	"errors"
+	"strconv"
 	"strings"
 
 	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
-	"github.com/eclipse/che-plugin-broker/model"
 	"github.com/google/uuid"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -21,6 +22,10 @@ type workspaceProperties struct {
 	started        bool
 	cheApiExternal string
 	exposureClass  string
+	// Whether the workspace volume is an emptyDir volume instead of the workspace persistent volume claim
+	ephemeralStorage bool
+	// Whether the objects are only rendered, so that objects the workspace depends on must not be created
+	rendering bool
 }
 
 func convertToCoreObjects(workspace *workspaceApi.Workspace) (*workspaceProperties, *workspaceApi.WorkspaceExposure, []ComponentInstanceStatus, []runtime.Object, error) {
@@ -31,11 +36,12 @@ func convertToCoreObjects(workspace *workspaceApi.Workspace) (*workspaceProperti
 	}
 
 	workspaceProperties := workspaceProperties{
-		namespace:     workspace.Namespace,
-		workspaceId:   "workspace" + strings.Join(strings.Split(uid.String(), "-")[0:3], ""),
-		workspaceName: workspace.Name,
-		started:       workspace.Spec.Started,
-		exposureClass: workspace.Spec.ExposureClass,
+		namespace:        workspace.Namespace,
+		workspaceId:      "workspace" + strings.Join(strings.Split(uid.String(), "-")[0:3], ""),
+		workspaceName:    workspace.Name,
+		started:          workspace.Spec.Started,
+		exposureClass:    workspace.Spec.ExposureClass,
+		ephemeralStorage: isEphemeralStorage(workspace.Spec.Devfile),
 	}
 
 	if !workspaceProperties.started {
@@ -62,7 +68,7 @@ func convertToCoreObjects(workspace *workspaceApi.Workspace) (*workspaceProperti
 		return &workspaceProperties, nil, nil, nil, err
 	}
 
-	err = setupPersistentVolumeClaim(workspace, mainDeployment)
+	err = setupPersistentVolumeClaim(workspaceProperties, mainDeployment)
 	if err != nil {
 		return &workspaceProperties, nil, nil, nil, err
 	}
@@ -73,13 +79,16 @@ func convertToCoreObjects(workspace *workspaceApi.Workspace) (*workspaceProperti
 	}
 	workspaceProperties.cheApiExternal = externalUrl
 
-	workspaceExposure, componentStatuses, k8sComponentsObjects, err := setupComponents(workspaceProperties, workspace.Spec.Devfile, mainDeployment)
+	workspaceExposure, componentStatuses, k8sComponentsObjects, err := setupComponents(workspaceProperties, devfileWithDefaultEditor(workspace), mainDeployment, resolvedPluginIds(workspace))
 	if err != nil {
 		return &workspaceProperties, nil, nil, nil, err
 	}
 	k8sComponentsObjects = append(k8sComponentsObjects, cheRestApisK8sObjects...)
+	k8sObjects := append(k8sComponentsObjects, mainDeployment)
+	addImagePullSecrets(workspaceImagePullSecrets(workspace), k8sObjects)
+	applySecurityProfiles(controllerConfig.getSecurityProfile(), k8sObjects, &mainDeployment.Spec.Template, sidecarContainers(componentStatuses))
 
-	return &workspaceProperties, workspaceExposure, componentStatuses, append(k8sComponentsObjects, mainDeployment), nil
+	return &workspaceProperties, workspaceExposure, componentStatuses, k8sObjects, nil
 }
 
 func buildMainDeployment(wkspProps workspaceProperties, workspace *workspaceApi.Workspace) (*appsv1.Deployment, error) {
@@ -90,7 +99,8 @@ func buildMainDeployment(wkspProps workspaceProperties, workspace *workspaceApi.
 		replicas = 1
 	}
 
-	var autoMountServiceAccount = serviceAccount != ""
+	// Outside of the full RBAC profile, only the che-rest-apis container gets the ServiceAccount token
+	var autoMountServiceAccount = controllerConfig.getRbacProfile() == rbacProfileFull
 
 	fromIntOne := intstr.FromInt(1)
 
@@ -137,33 +147,71 @@ func buildMainDeployment(wkspProps workspaceProperties, workspace *workspaceApi.
 			},
 		},
 	}
-	if serviceAccount != "" {
-		deploy.Spec.Template.Spec.ServiceAccountName = serviceAccount
-	}
+	deploy.Spec.Template.Spec.ServiceAccountName = workspaceServiceAccountName(workspace)
 
 	return &deploy, nil
 }
 
-func setupPersistentVolumeClaim(workspace *workspaceApi.Workspace, deployment *appsv1.Deployment) error {
-	var workspaceClaim = corev1.PersistentVolumeClaimVolumeSource{
-		ClaimName: "claim-che-workspace",
-	}
+func setupPersistentVolumeClaim(wkspProps workspaceProperties, deployment *appsv1.Deployment) error {
 	deployment.Spec.Template.Spec.Volumes = []corev1.Volume{
-		corev1.Volume{
+		workspaceVolume(wkspProps),
+	}
+	return nil
+}
+
+// workspaceVolume returns the volume that contains the projects, plugins and devfile volumes of the workspace.
+// It is the workspace persistent volume claim, unless the devfile sets the `persistVolumes` attribute to "false",
+// in which case the data of the workspace is lost when the workspace stops.
+func workspaceVolume(wkspProps workspaceProperties) corev1.Volume {
+	if wkspProps.ephemeralStorage {
+		return corev1.Volume{
 			Name: "claim-che-workspace",
 			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &workspaceClaim,
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}
+	}
+	return corev1.Volume{
+		Name: "claim-che-workspace",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: "claim-che-workspace",
 			},
 		},
 	}
-	return nil
 }
 
-func setupComponents(names workspaceProperties, devfile workspaceApi.DevFileSpec, deployment *appsv1.Deployment) (*workspaceApi.WorkspaceExposure, []ComponentInstanceStatus, []runtime.Object, error) {
+func isEphemeralStorage(devfile workspaceApi.DevFileSpec) bool {
+	return devfile.Attributes[workspaceApi.PersistVolumesAttribute] == "false"
+}
+
+// resolvedPluginIds returns the concrete plugin ids that the plugin components were resolved to
+// when the workspace was started, so that they stay the same until the workspace is restarted.
+func resolvedPluginIds(workspace *workspaceApi.Workspace) map[string]string {
+	resolvedIds := map[string]string{}
+	statusesAnnotation := workspace.Status.AdditionalInfo["org.eclipse.che.workspace/componentstatuses"]
+	if statusesAnnotation == "" {
+		return resolvedIds
+	}
+	statuses := []ComponentInstanceStatus{}
+	err := json.Unmarshal([]byte(statusesAnnotation), &statuses)
+	if err != nil {
+		log.Error(err, "")
+		return resolvedIds
+	}
+	for _, status := range statuses {
+		if status.PluginId != "" && status.ResolvedPluginId != "" {
+			resolvedIds[status.PluginId] = status.ResolvedPluginId
+		}
+	}
+	return resolvedIds
+}
+
+func setupComponents(names workspaceProperties, devfile workspaceApi.DevFileSpec, deployment *appsv1.Deployment, resolvedPluginIds map[string]string) (*workspaceApi.WorkspaceExposure, []ComponentInstanceStatus, []runtime.Object, error) {
 	components := devfile.Components
 	k8sObjects := []runtime.Object {}
 
-	pluginFQNs := []model.PluginFQN{}
+	extensions := []ExtensionDownload{}
 
 	componentInstanceStatuses := []ComponentInstanceStatus{}
 
@@ -173,37 +221,35 @@ func setupComponents(names workspaceProperties, devfile workspaceApi.DevFileSpec
 		var componentInstanceStatus *ComponentInstanceStatus
 		switch componentType {
 		case "cheEditor", "chePlugin":
-			componentInstanceStatus, err = setupChePlugin(names, &component)
+			componentInstanceStatus, err = setupChePlugin(names, &component, resolvedPluginIds)
 			if err != nil {
 				return nil, nil, nil, err
 			}
-			if componentInstanceStatus.PluginFQN != nil {
-				pluginFQNs = append(pluginFQNs, *componentInstanceStatus.PluginFQN)
-			}
+			extensions = append(extensions, componentInstanceStatus.Extensions...)
 			break
 		case "kubernetes", "openshift":
 			componentInstanceStatus, err = setupK8sLikeComponent(names, &component)
 			break
 		case "dockerimage":
-			componentInstanceStatus, err = setupDockerImageComponent(names, devfile.Commands, &component)
+			componentInstanceStatus, err = setupDockerImageComponent(names, &component)
 			break
 		}
 		if err != nil {
 			return nil, nil, nil, err
 		}
+		componentInstanceStatus.Alias = emptyIfNil(component.Alias)
+		componentInstanceStatus.ComponentType = component.Type
+		componentInstanceStatus.AutomountWorkspaceSecrets = component.AutomountWorkspaceSecrets
 		k8sObjects = append(k8sObjects, componentInstanceStatus.ExternalObjects...)
 		componentInstanceStatuses = append(componentInstanceStatuses, *componentInstanceStatus)
 	}
 
-	mergeWorkspaceAdditions(deployment, componentInstanceStatuses, k8sObjects)
+	contributeDevfileCommands(names, devfile.Commands, componentInstanceStatuses)
 
-	precreateSubpathsInitContainer(names, &deployment.Spec.Template.Spec)
-	initContainersK8sObjects, err := setupPluginInitContainers(names, &deployment.Spec.Template.Spec, pluginFQNs)
-	if err != nil {
-		return nil, nil, nil, err
-	}
+	mergeWorkspaceAdditions(deployment, componentInstanceStatuses)
 
-	k8sObjects = append(k8sObjects, initContainersK8sObjects...)
+	precreateSubpathsInitContainer(names, &deployment.Spec.Template.Spec, controllerConfig.getSecurityProfile())
+	setupPluginsCopyInitContainer(names, &deployment.Spec.Template.Spec, extensions)
 
 	workspaceExposure := buildWorkspaceExposure(names, componentInstanceStatuses)
 
@@ -262,18 +308,24 @@ func buildWorkspaceExposure(wkspProperties workspaceProperties, componentInstanc
 // Penser au admission controller pour ajouter le nom du user dnas le workspace ? E tout cas ajouter le nom du
 // users dans la custom resource du workspace. + la classe de workspace exposure.
 
-func precreateSubpathsInitContainer(names workspaceProperties, podSpec *corev1.PodSpec) {
+// precreateSubpathsInitContainer adds an init container that creates the directory of the workspace
+// in the workspace volume. When the security profile sets a filesystem group, the directory belongs to this group,
+// and the setgid bit makes the directory, and the sub-paths created in it, writable by all the containers of the pod.
+// Otherwise the containers may run as any user, and the directory is writable by everyone.
+func precreateSubpathsInitContainer(names workspaceProperties, podSpec *corev1.PodSpec, profile securityProfile) {
+	workspaceDir := "/tmp/che-workspaces/" + names.workspaceId
+	command := []string{"/usr/bin/mkdir"}
+	args := []string{"-p", "-v", "-m", "777", workspaceDir}
+	if profile.fsGroup != nil {
+		command = []string{"/bin/sh", "-c"}
+		fsGroup := strconv.FormatInt(*profile.fsGroup, 10)
+		args = []string{"mkdir -p -v " + workspaceDir + " && chgrp " + fsGroup + " " + workspaceDir + " && chmod 2770 " + workspaceDir}
+	}
 	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
-		Name:    "precreate-subpaths",
-		Image:   "registry.access.redhat.com/ubi8/ubi-minimal",
-		Command: []string{"/usr/bin/mkdir"},
-		Args: []string{
-			"-p",
-			"-v",
-			"-m",
-			"777",
-			"/tmp/che-workspaces/" + names.workspaceId,
-		},
+		Name:            "precreate-subpaths",
+		Image:           "registry.access.redhat.com/ubi8/ubi-minimal",
+		Command:         command,
+		Args:            args,
 		ImagePullPolicy: corev1.PullIfNotPresent,
 		VolumeMounts: []corev1.VolumeMount{
 			corev1.VolumeMount{
@@ -286,7 +338,7 @@ func precreateSubpathsInitContainer(names workspaceProperties, podSpec *corev1.P
 	})
 }
 
-func mergeWorkspaceAdditions(workspaceDeployment *appsv1.Deployment, componentInstanceStatuses []ComponentInstanceStatus, k8sObjects []runtime.Object) error {
+func mergeWorkspaceAdditions(workspaceDeployment *appsv1.Deployment, componentInstanceStatuses []ComponentInstanceStatus) error {
 	workspacePodAdditions := []corev1.PodTemplateSpec{}
 	for _, componentInstanceStatus := range componentInstanceStatuses {
 		if componentInstanceStatus.WorkspacePodAdditions == nil {
@@ -342,11 +394,5 @@ func mergeWorkspaceAdditions(workspaceDeployment *appsv1.Deployment, componentIn
 		}
 	}
 	workspacePodTemplate.Labels[DEPLOYMENT_NAME_LABEL] = workspaceDeployment.Name
-	for _, externalObject := range k8sObjects {
-		service, isAService := externalObject.(*corev1.Service)
-		if isAService {
-			service.Spec.Selector[DEPLOYMENT_NAME_LABEL] = workspaceDeployment.Name
-		}
-	}
 	return nil
 }
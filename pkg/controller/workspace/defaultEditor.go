@@ -0,0 +1,72 @@
+package workspace
+
+import (
+	"strings"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+)
+
+// devfileWithDefaultEditor returns the devfile of the workspace, completed with the default editor
+// and default plugins of the controller config when it has no `cheEditor` component.
+// The workspace spec itself is left unchanged, so that the defaults follow the controller config.
+// Injection can be disabled with the `org.eclipse.che.workspace/skip-default-editor` annotation,
+// or with the `editorFree` devfile attribute.
+func devfileWithDefaultEditor(workspace *workspaceApi.Workspace) workspaceApi.DevFileSpec {
+	devfile := workspace.Spec.Devfile
+	if isEditorFree(workspace) {
+		return devfile
+	}
+
+	existingPlugins := map[string]bool{}
+	for _, component := range devfile.Components {
+		switch component.Type {
+		case workspaceApi.CheEditor:
+			return devfile
+		case workspaceApi.ChePlugin:
+			if component.Id != nil {
+				existingPlugins[pluginPublisherAndName(*component.Id)] = true
+			}
+		}
+	}
+
+	editorId := controllerConfig.getDefaultEditor()
+	if editorId == "" {
+		return devfile
+	}
+
+	components := []workspaceApi.ComponentSpec{}
+	components = append(components, devfile.Components...)
+	components = append(components, defaultPluginComponent(workspaceApi.CheEditor, editorId))
+	for _, pluginId := range controllerConfig.getDefaultPlugins() {
+		if existingPlugins[pluginPublisherAndName(pluginId)] {
+			continue
+		}
+		components = append(components, defaultPluginComponent(workspaceApi.ChePlugin, pluginId))
+	}
+	devfile.Components = components
+	return devfile
+}
+
+// isEditorFree returns whether the user opted out of the default editor
+func isEditorFree(workspace *workspaceApi.Workspace) bool {
+	return workspace.Annotations[SKIP_DEFAULT_EDITOR_ANNOTATION] == "true" ||
+		workspace.Spec.Devfile.Attributes[workspaceApi.EditorFreeAttribute] == "true"
+}
+
+func defaultPluginComponent(componentType workspaceApi.DevfileName, pluginId string) workspaceApi.ComponentSpec {
+	id := pluginId
+	return workspaceApi.ComponentSpec{
+		Type: componentType,
+		Id:   &id,
+	}
+}
+
+// pluginPublisherAndName returns the `{publisher}/{name}` part of a plugin id,
+// ignoring its registry and version.
+func pluginPublisherAndName(pluginId string) string {
+	idParts := strings.Split(pluginId, "/")
+	if len(idParts) < 3 {
+		return pluginId
+	}
+	return strings.Join(idParts[len(idParts)-3:len(idParts)-1], "/")
+}
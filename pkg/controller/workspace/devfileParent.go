@@ -0,0 +1,262 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Maximum number of parents in the chain of parents of a devfile
+const maxDevfileParents = 10
+
+// resolveWorkspaceDevfile replaces the devfile of the workspace with the devfile it effectively uses:
+// the devfile referenced by its `devfileSource` if any, flattened with its parents.
+// The resulting devfile is then validated.
+func resolveWorkspaceDevfile(clt client.Client, workspace *workspaceApi.Workspace) error {
+	if err := resolveDevfileSource(clt, workspace); err != nil {
+		return err
+	}
+	if workspace.Spec.Template != "" {
+		if workspace.Spec.Devfile.Parent != nil {
+			return errors.New("The devfile of a workspace based on a template cannot have a parent")
+		}
+		workspace.Spec.Devfile.Parent = &workspaceApi.DevfileSource{
+			Template: workspace.Spec.Template,
+		}
+	}
+	if err := flattenDevfileParents(clt, workspace); err != nil {
+		return err
+	}
+	return validateDevfile(workspace.Spec.Devfile)
+}
+
+// flattenDevfileParents merges the parents of the workspace devfile into it.
+// The flattened parent is kept in the workspace status, and is only fetched again when the workspace starts
+// or when the devfile references another parent: changes to the parents are applied on the next start,
+// while changes to the devfile itself are applied right away.
+func flattenDevfileParents(clt client.Client, workspace *workspaceApi.Workspace) error {
+	if workspace.Spec.Devfile.Parent == nil {
+		workspace.Status.FlattenedDevfileParent = ""
+		workspace.Status.FlattenedDevfileParentSource = ""
+		return nil
+	}
+	parentJson, err := json.Marshal(workspace.Spec.Devfile.Parent)
+	if err != nil {
+		return err
+	}
+
+	parent := workspaceApi.DevFileSpec{}
+	if workspace.Status.FlattenedDevfileParent != "" && workspace.Status.FlattenedDevfileParentSource == string(parentJson) && !isStarting(workspace) {
+		if err := yaml.Unmarshal([]byte(workspace.Status.FlattenedDevfileParent), &parent); err != nil {
+			return err
+		}
+	} else {
+		log.Info("Fetching the parents of the devfile of workspace '" + workspace.Name + "'")
+		parent, err = fetchFlattenedParent(clt, workspace.Namespace, workspace.Spec.Devfile.Parent, []string{})
+		if err != nil {
+			return err
+		}
+		parentYaml, err := yaml.Marshal(parent)
+		if err != nil {
+			return err
+		}
+		workspace.Status.FlattenedDevfileParent = string(parentYaml)
+		workspace.Status.FlattenedDevfileParentSource = string(parentJson)
+	}
+
+	flattened, err := mergeDevfiles(parent, workspace.Spec.Devfile)
+	if err != nil {
+		return err
+	}
+	workspace.Spec.Devfile = flattened
+	return nil
+}
+
+// flattenDevfile recursively merges the devfile into its parent.
+// The sources of the devfiles already visited are used to detect inheritance cycles.
+func flattenDevfile(clt client.Client, namespace string, devfile workspaceApi.DevFileSpec, visitedParents []string) (workspaceApi.DevFileSpec, error) {
+	if devfile.Parent == nil {
+		return devfile, nil
+	}
+	parent, err := fetchFlattenedParent(clt, namespace, devfile.Parent, visitedParents)
+	if err != nil {
+		return devfile, err
+	}
+	return mergeDevfiles(parent, devfile)
+}
+
+// fetchFlattenedParent fetches the parent devfile and merges it into its own parents
+func fetchFlattenedParent(clt client.Client, namespace string, source *workspaceApi.DevfileSource, visitedParents []string) (workspaceApi.DevFileSpec, error) {
+	parentJson, err := json.Marshal(source)
+	if err != nil {
+		return workspaceApi.DevFileSpec{}, err
+	}
+	for _, visitedParent := range visitedParents {
+		if visitedParent == string(parentJson) {
+			return workspaceApi.DevFileSpec{}, errors.New("Cycle in the devfile parents: " + string(parentJson))
+		}
+	}
+	if len(visitedParents) >= maxDevfileParents {
+		return workspaceApi.DevFileSpec{}, errors.New("Too many devfile parents")
+	}
+
+	content, err := fetchDevfile(clt, namespace, source)
+	if err != nil {
+		return workspaceApi.DevFileSpec{}, errors.New("Cannot fetch the devfile parent " + string(parentJson) + ": " + err.Error())
+	}
+	parent, err := parseFetchedDevfile(source, content)
+	if err != nil {
+		return workspaceApi.DevFileSpec{}, errors.New("Invalid devfile parent " + string(parentJson) + ": " + err.Error())
+	}
+	return flattenDevfile(clt, namespace, parent, append(visitedParents, string(parentJson)))
+}
+
+// mergeDevfiles merges a devfile into its parent. Components are merged by alias, commands and projects by name.
+// A component with the alias of a parent component overrides its env, memory limit, endpoints and volumes.
+// A command or a project with the name of a parent one replaces it.
+// The apiVersion and metadata of the parent are kept when the devfile doesn't set them,
+// and the attributes of the devfile override the parent ones.
+func mergeDevfiles(parent workspaceApi.DevFileSpec, child workspaceApi.DevFileSpec) (workspaceApi.DevFileSpec, error) {
+	merged := *child.DeepCopy()
+	merged.Parent = nil
+	if merged.ApiVersion == "" {
+		merged.ApiVersion = parent.ApiVersion
+	}
+	if merged.Metadata.Name == "" && merged.Metadata.GenerateName == "" {
+		merged.Metadata = parent.Metadata
+	}
+	if len(parent.Attributes) > 0 {
+		merged.Attributes = map[string]string{}
+		for name, value := range parent.Attributes {
+			merged.Attributes[name] = value
+		}
+		for name, value := range child.Attributes {
+			merged.Attributes[name] = value
+		}
+	}
+
+	parentComponentIndexes := map[string]int{}
+	merged.Components = []workspaceApi.ComponentSpec{}
+	for _, component := range parent.Components {
+		if component.Alias != nil {
+			parentComponentIndexes[*component.Alias] = len(merged.Components)
+		}
+		merged.Components = append(merged.Components, component)
+	}
+	for _, component := range child.Components {
+		if component.Alias == nil {
+			merged.Components = append(merged.Components, component)
+			continue
+		}
+		index, overridesParent := parentComponentIndexes[*component.Alias]
+		if !overridesParent {
+			merged.Components = append(merged.Components, component)
+			continue
+		}
+		overridden, err := overrideComponent(merged.Components[index], component)
+		if err != nil {
+			return merged, err
+		}
+		merged.Components[index] = overridden
+	}
+
+	childCommands := map[string]bool{}
+	for _, command := range child.Commands {
+		childCommands[command.Name] = true
+	}
+	merged.Commands = []workspaceApi.CommandSpec{}
+	for _, command := range parent.Commands {
+		if !childCommands[command.Name] {
+			merged.Commands = append(merged.Commands, command)
+		}
+	}
+	merged.Commands = append(merged.Commands, child.Commands...)
+
+	childProjects := map[string]bool{}
+	for _, project := range child.Projects {
+		childProjects[project.Name] = true
+	}
+	merged.Projects = []workspaceApi.ProjectSpec{}
+	for _, project := range parent.Projects {
+		if !childProjects[project.Name] {
+			merged.Projects = append(merged.Projects, project)
+		}
+	}
+	merged.Projects = append(merged.Projects, child.Projects...)
+
+	return merged, nil
+}
+
+// overrideComponent applies the env, memory limit, endpoints and volumes of a component to the parent component
+// with the same alias. Env variables, endpoints and volumes are merged by name.
+func overrideComponent(parent workspaceApi.ComponentSpec, override workspaceApi.ComponentSpec) (workspaceApi.ComponentSpec, error) {
+	if (override.Type != "" && override.Type != parent.Type) ||
+		override.Id != nil || override.Image != nil || override.Reference != nil || override.ReferenceContent != nil ||
+		override.MountSources != nil || override.Selector != nil || override.Command != nil || override.Args != nil ||
+		override.RegistryUrl != nil || override.AutomountWorkspaceSecrets != nil || override.Entrypoints != nil {
+		return parent, errors.New("Component '" + *override.Alias + "' can only override the env, memoryLimit, endpoints and volumes of the parent component")
+	}
+
+	overridden := *parent.DeepCopy()
+	if override.MemoryLimit != nil {
+		overridden.MemoryLimit = override.MemoryLimit
+	}
+
+	for _, env := range override.Env {
+		replaced := false
+		for i := range overridden.Env {
+			if overridden.Env[i].Name == env.Name {
+				overridden.Env[i] = env
+				replaced = true
+			}
+		}
+		if !replaced {
+			overridden.Env = append(overridden.Env, env)
+		}
+	}
+
+	overridden.EnvFrom = append(overridden.EnvFrom, override.EnvFrom...)
+
+	for _, endpoint := range override.Endpoints {
+		replaced := false
+		for i := range overridden.Endpoints {
+			if overridden.Endpoints[i].Name == endpoint.Name {
+				overridden.Endpoints[i] = endpoint
+				replaced = true
+			}
+		}
+		if !replaced {
+			overridden.Endpoints = append(overridden.Endpoints, endpoint)
+		}
+	}
+
+	for _, volume := range override.Volumes {
+		replaced := false
+		for i := range overridden.Volumes {
+			if overridden.Volumes[i].Name == volume.Name {
+				overridden.Volumes[i] = volume
+				replaced = true
+			}
+		}
+		if !replaced {
+			overridden.Volumes = append(overridden.Volumes, volume)
+		}
+	}
+
+	return overridden, nil
+}
+
+// isStarting returns whether the workspace should start, but has not started yet
+func isStarting(workspace *workspaceApi.Workspace) bool {
+	if !workspace.Spec.Started {
+		return false
+	}
+	switch workspace.Status.Phase {
+	case "", workspaceApi.WorkspacePhaseStopped, workspaceApi.WorkspacePhaseFailed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package workspace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Default file name of the devfile in git repositories and ConfigMaps
+const defaultDevfileName = "devfile.yaml"
+
+// Maximum size of a devfile fetched from a devfile source
+const maxDevfileSize = 1024 * 1024
+
+var devfileHttpClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialDevfileHost,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// Address ranges, in addition to loopback, link-local, multicast and unspecified addresses,
+// that devfiles can't be fetched from unless their host is allowed in the `devfile.source.allowed.hosts` property
+var internalNetworks = parseNetworks("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "0.0.0.0/8", "fc00::/7")
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func isInternalAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialDevfileHost opens the connections used to fetch devfiles, including after redirects.
+// Connections to the internal addresses of the cluster or of the node are refused,
+// unless the host is allowed in the `devfile.source.allowed.hosts` property.
+// The address is checked once resolved, so that a host can't resolve to another address when connecting.
+func dialDevfileHost(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	for _, allowedHost := range controllerConfig.getDevfileSourceAllowedHosts() {
+		if strings.ToLower(host) == allowedHost {
+			return dialer.DialContext(ctx, network, address)
+		}
+	}
+	dialer.Control = func(network string, address string, _ syscall.RawConn) error {
+		ip, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if parsed := net.ParseIP(ip); parsed == nil || isInternalAddress(parsed) {
+			return errors.New("devfiles cannot be fetched from the internal address of host '" + host + "'")
+		}
+		return nil
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// resolveDevfileSource replaces the devfile of a workspace that has a `devfileSource` with the devfile it references.
+// The resolved devfile is kept in the workspace status, and is only fetched again when the source changes,
+// or when the value of the `org.eclipse.che.workspace/refresh-devfile` annotation changes.
+// ConfigMap and DevfileTemplate sources can't be resolved without a client.
+func resolveDevfileSource(clt client.Client, workspace *workspaceApi.Workspace) error {
+	source := workspace.Spec.DevfileSource
+	if source == nil {
+		delete(workspace.Status.AdditionalInfo, DEVFILE_ADDITIONAL_INFO)
+		delete(workspace.Status.AdditionalInfo, DEVFILE_SOURCE_ADDITIONAL_INFO)
+		delete(workspace.Status.AdditionalInfo, DEVFILE_REFRESH_ADDITIONAL_INFO)
+		workspace.Status.DevfileSourceHash = ""
+		return nil
+	}
+	if devfile := workspace.Spec.Devfile; len(devfile.Components) > 0 || len(devfile.Commands) > 0 || len(devfile.Projects) > 0 {
+		return errors.New("The workspace cannot define both a devfile and a devfileSource")
+	}
+
+	if workspace.Status.AdditionalInfo == nil {
+		workspace.Status.AdditionalInfo = map[string]string{}
+	}
+	additionalInfo := workspace.Status.AdditionalInfo
+
+	sourceJson, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+	refresh := workspace.Annotations[REFRESH_DEVFILE_ANNOTATION]
+	content := additionalInfo[DEVFILE_ADDITIONAL_INFO]
+	if content == "" ||
+		additionalInfo[DEVFILE_SOURCE_ADDITIONAL_INFO] != string(sourceJson) ||
+		additionalInfo[DEVFILE_REFRESH_ADDITIONAL_INFO] != refresh {
+		log.Info("Fetching the devfile of workspace '" + workspace.Name + "' from its devfileSource")
+		fetched, err := fetchDevfile(clt, workspace.Namespace, source)
+		if err != nil {
+			return errors.New("Cannot resolve the devfileSource: " + err.Error())
+		}
+		// Only the parsed devfile is kept in the status, never the content as it was fetched
+		fetchedDevfile, err := parseFetchedDevfile(source, fetched)
+		if err != nil {
+			return errors.New("Invalid devfile resolved from the devfileSource: " + err.Error())
+		}
+		canonical, err := yaml.Marshal(fetchedDevfile)
+		if err != nil {
+			return err
+		}
+		content = string(canonical)
+		hash := sha256.Sum256(canonical)
+		additionalInfo[DEVFILE_ADDITIONAL_INFO] = content
+		additionalInfo[DEVFILE_SOURCE_ADDITIONAL_INFO] = string(sourceJson)
+		additionalInfo[DEVFILE_REFRESH_ADDITIONAL_INFO] = refresh
+		workspace.Status.DevfileSourceHash = hex.EncodeToString(hash[:])
+	}
+
+	devfile := workspaceApi.DevFileSpec{}
+	if err := yaml.Unmarshal([]byte(content), &devfile); err != nil {
+		return errors.New("Invalid devfile resolved from the devfileSource: " + err.Error())
+	}
+	workspace.Spec.Devfile = devfile
+	return nil
+}
+
+// parseFetchedDevfile parses the content fetched from a devfile source.
+// Content downloaded from a URL or a git repository is expected to be a devfile with an `apiVersion`.
+func parseFetchedDevfile(source *workspaceApi.DevfileSource, content []byte) (workspaceApi.DevFileSpec, error) {
+	devfile := workspaceApi.DevFileSpec{}
+	if err := yaml.Unmarshal(content, &devfile); err != nil {
+		return devfile, err
+	}
+	if (source.Url != "" || source.Git != nil) && devfile.ApiVersion == "" {
+		return devfile, errors.New("the fetched content is not a devfile: the apiVersion is missing")
+	}
+	return devfile, nil
+}
+
+func fetchDevfile(clt client.Client, namespace string, source *workspaceApi.DevfileSource) ([]byte, error) {
+	setFields := 0
+	for _, isSet := range []bool{source.Url != "", source.Git != nil, source.ConfigMap != nil, source.Template != ""} {
+		if isSet {
+			setFields++
+		}
+	}
+	if setFields != 1 {
+		return nil, errors.New("exactly one of url, git, configMap and template should be set")
+	}
+
+	switch {
+	case source.Url != "":
+		return fetchDevfileFromUrl(source.Url)
+	case source.Git != nil:
+		rawUrl, err := gitRawFileUrl(*source.Git)
+		if err != nil {
+			return nil, err
+		}
+		return fetchDevfileFromUrl(rawUrl)
+	case source.ConfigMap != nil:
+		return fetchDevfileFromConfigMap(clt, namespace, *source.ConfigMap)
+	default:
+		return fetchDevfileFromTemplate(clt, source.Template)
+	}
+}
+
+func fetchDevfileFromUrl(devfileUrl string) ([]byte, error) {
+	if !strings.HasPrefix(devfileUrl, "http://") && !strings.HasPrefix(devfileUrl, "https://") {
+		return nil, errors.New("unsupported devfile URL '" + devfileUrl + "': only HTTP and HTTPS URLs are supported")
+	}
+	resp, err := devfileHttpClient.Get(devfileUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("cannot download '" + devfileUrl + "': " + resp.Status)
+	}
+	content, err := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: maxDevfileSize + 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxDevfileSize {
+		return nil, errors.New("the devfile at '" + devfileUrl + "' is too large")
+	}
+	return content, nil
+}
+
+// gitRawFileUrl returns the URL at which the raw devfile can be downloaded from the repository.
+// GitHub repositories use the `raw.githubusercontent.com` host, and other repositories
+// are expected to follow the GitLab layout.
+func gitRawFileUrl(source workspaceApi.GitDevfileSource) (string, error) {
+	repoUrl, err := url.Parse(source.Repository)
+	if err != nil || (repoUrl.Scheme != "http" && repoUrl.Scheme != "https") {
+		return "", errors.New("unsupported git repository '" + source.Repository + "': only HTTP and HTTPS repository URLs are supported")
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(repoUrl.Path, "/"), ".git")
+	revision := source.Revision
+	if revision == "" {
+		revision = "HEAD"
+	}
+	devfilePath := strings.TrimPrefix(source.Path, "/")
+	if devfilePath == "" {
+		devfilePath = defaultDevfileName
+	}
+
+	if repoUrl.Host == "github.com" {
+		return "https://raw.githubusercontent.com/" + repoPath + "/" + revision + "/" + devfilePath, nil
+	}
+	return repoUrl.Scheme + "://" + repoUrl.Host + "/" + repoPath + "/-/raw/" + revision + "/" + devfilePath, nil
+}
+
+func fetchDevfileFromConfigMap(clt client.Client, namespace string, source workspaceApi.ConfigMapDevfileSource) ([]byte, error) {
+	if clt == nil {
+		return nil, errors.New("ConfigMap devfile sources can only be resolved in the cluster")
+	}
+	key := source.Key
+	if key == "" {
+		key = defaultDevfileName
+	}
+	configMap := &corev1.ConfigMap{}
+	err := clt.Get(context.TODO(), types.NamespacedName{Name: source.Name, Namespace: namespace}, configMap)
+	if err != nil {
+		return nil, err
+	}
+	content, exists := configMap.Data[key]
+	if !exists {
+		return nil, errors.New("the '" + source.Name + "' ConfigMap has no '" + key + "' key")
+	}
+	return []byte(content), nil
+}
+
+func fetchDevfileFromTemplate(clt client.Client, templateName string) ([]byte, error) {
+	if clt == nil {
+		return nil, errors.New("DevfileTemplate devfile sources can only be resolved in the cluster")
+	}
+	template := &workspaceApi.DevfileTemplate{}
+	err := clt.Get(context.TODO(), types.NamespacedName{Name: templateName}, template)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(template.Spec.Devfile)
+}
@@ -0,0 +1,107 @@
+package workspace
+
+import (
+	"errors"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// validateDevfile checks the constraints of the devfile 1.0 format that the CRD schema cannot express,
+// such as the fields required by each component type, or the uniqueness of component aliases.
+func validateDevfile(devfile workspaceApi.DevFileSpec) error {
+	if devfile.ApiVersion != "" && devfile.ApiVersion != workspaceApi.DevfileApiVersion {
+		return errors.New("Unsupported devfile apiVersion '" + devfile.ApiVersion + "': only " + workspaceApi.DevfileApiVersion + " is supported")
+	}
+
+	aliases := map[string]bool{}
+	for _, component := range devfile.Components {
+		if component.Alias != nil {
+			if aliases[*component.Alias] {
+				return errors.New("Duplicate component alias: " + *component.Alias)
+			}
+			aliases[*component.Alias] = true
+		}
+		if err := validateComponent(component); err != nil {
+			if component.Alias != nil {
+				return errors.New("Invalid component '" + *component.Alias + "': " + err.Error())
+			}
+			return errors.New("Invalid " + string(component.Type) + " component: " + err.Error())
+		}
+	}
+
+	commandNames := map[string]bool{}
+	for _, command := range devfile.Commands {
+		if commandNames[command.Name] {
+			return errors.New("Duplicate command name: " + command.Name)
+		}
+		commandNames[command.Name] = true
+		if len(command.Actions) == 0 {
+			return errors.New("Command '" + command.Name + "' has no action")
+		}
+		for _, action := range command.Actions {
+			if action.Component != nil && !aliases[*action.Component] {
+				return errors.New("Command '" + command.Name + "' refers to the unknown component '" + *action.Component + "'")
+			}
+		}
+	}
+
+	projectNames := map[string]bool{}
+	for _, project := range devfile.Projects {
+		if projectNames[project.Name] {
+			return errors.New("Duplicate project name: " + project.Name)
+		}
+		projectNames[project.Name] = true
+		switch project.Source.Type {
+		case "git", "github", "zip":
+		default:
+			return errors.New("Project '" + project.Name + "' has the unsupported source type '" + project.Source.Type + "'")
+		}
+	}
+	return nil
+}
+
+func validateComponent(component workspaceApi.ComponentSpec) error {
+	switch component.Type {
+	case workspaceApi.CheEditor, workspaceApi.ChePlugin:
+		if component.Id == nil {
+			return errors.New("the id is required")
+		}
+	case workspaceApi.Dockerimage:
+		if component.Image == nil {
+			return errors.New("the image is required")
+		}
+	case workspaceApi.Kubernetes, workspaceApi.Openshift:
+		if component.Reference == nil && component.ReferenceContent == nil {
+			return errors.New("either the reference or the referenceContent is required")
+		}
+	default:
+		return errors.New("unknown component type '" + string(component.Type) + "'")
+	}
+
+	if len(component.Entrypoints) > 0 {
+		if component.Type != workspaceApi.Kubernetes && component.Type != workspaceApi.Openshift {
+			return errors.New("entrypoints are only supported by kubernetes and openshift components")
+		}
+		if component.Command != nil || component.Args != nil {
+			return errors.New("entrypoints cannot be combined with the command and args of the component")
+		}
+	}
+	for _, env := range component.Env {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if env.Value != "" {
+			return errors.New("the env variable '" + env.Name + "' cannot have both a value and a valueFrom")
+		}
+		if (env.ValueFrom.SecretKeyRef == nil) == (env.ValueFrom.ConfigMapKeyRef == nil) {
+			return errors.New("the valueFrom of the env variable '" + env.Name + "' should have exactly one of secretKeyRef and configMapKeyRef")
+		}
+	}
+	if component.MemoryLimit != nil && *component.MemoryLimit != "" {
+		if _, err := resource.ParseQuantity(*component.MemoryLimit); err != nil {
+			return errors.New("invalid memoryLimit '" + *component.MemoryLimit + "'")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// TestRealWorldDevfiles checks that the devfiles of testdata/devfiles, taken from real-world stacks,
+// are fully described by the devfile types, and are valid.
+func TestRealWorldDevfiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "devfiles", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("No devfile found in testdata/devfiles")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			devfile := workspaceApi.DevFileSpec{}
+			if err := yaml.UnmarshalStrict(content, &devfile); err != nil {
+				t.Fatalf("The devfile doesn't match the devfile types: %v", err)
+			}
+			if devfile.ApiVersion != workspaceApi.DevfileApiVersion {
+				t.Errorf("Expected apiVersion %s, got '%s'", workspaceApi.DevfileApiVersion, devfile.ApiVersion)
+			}
+			if devfile.Metadata.Name == "" && devfile.Metadata.GenerateName == "" {
+				t.Error("Expected the metadata to have a name or a generateName")
+			}
+			if err := validateDevfile(devfile); err != nil {
+				t.Errorf("Expected the devfile to be valid: %v", err)
+			}
+
+			marshalled, err := yaml.Marshal(devfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roundTripped := workspaceApi.DevFileSpec{}
+			if err := yaml.UnmarshalStrict(marshalled, &roundTripped); err != nil {
+				t.Fatalf("The marshalled devfile doesn't match the devfile types: %v", err)
+			}
+			if !reflect.DeepEqual(devfile, roundTripped) {
+				t.Errorf("The devfile changed after a round trip:\n%s", string(marshalled))
+			}
+		})
+	}
+}
+
+func TestValidateDevfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		devfile string
+		valid   bool
+	}{
+		{
+			name: "minimal devfile",
+			devfile: `
+apiVersion: 1.0.0
+metadata:
+  name: minimal
+`,
+			valid: true,
+		},
+		{
+			name: "unsupported apiVersion",
+			devfile: `
+apiVersion: 0.0.1
+metadata:
+  name: old
+`,
+		},
+		{
+			name: "unknown component type",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: container
+    image: busybox
+`,
+		},
+		{
+			name: "duplicate component alias",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: dockerimage
+    alias: tools
+    image: busybox
+  - type: dockerimage
+    alias: tools
+    image: alpine
+`,
+		},
+		{
+			name: "dockerimage without image",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: dockerimage
+    alias: tools
+`,
+		},
+		{
+			name: "kubernetes without reference",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: kubernetes
+    alias: db
+`,
+		},
+		{
+			name: "plugin without id",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: chePlugin
+    reference: https://example.com/meta.yaml
+`,
+		},
+		{
+			name: "entrypoints of a dockerimage",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: dockerimage
+    image: busybox
+    entrypoints:
+      - containerName: busybox
+        command: ['sleep']
+`,
+		},
+		{
+			name: "invalid memory limit",
+			devfile: `
+apiVersion: 1.0.0
+components:
+  - type: dockerimage
+    image: busybox
+    memoryLimit: lots
+`,
+		},
+		{
+			name: "duplicate command name",
+			devfile: `
+apiVersion: 1.0.0
+commands:
+  - name: build
+    actions:
+      - type: exec
+        command: make
+  - name: build
+    actions:
+      - type: exec
+        command: make all
+`,
+		},
+		{
+			name: "command of an unknown component",
+			devfile: `
+apiVersion: 1.0.0
+commands:
+  - name: build
+    actions:
+      - type: exec
+        component: tools
+        command: make
+`,
+		},
+		{
+			name: "unsupported project source",
+			devfile: `
+apiVersion: 1.0.0
+projects:
+  - name: project
+    source:
+      type: svn
+      location: https://example.com/svn/project
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			devfile := workspaceApi.DevFileSpec{}
+			if err := yaml.UnmarshalStrict([]byte(test.devfile), &devfile); err != nil {
+				t.Fatal(err)
+			}
+			err := validateDevfile(devfile)
+			if test.valid && err != nil {
+				t.Errorf("Expected the devfile to be valid: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("Expected the devfile to be invalid")
+			}
+		})
+	}
+}
+
+func TestEphemeralStorage(t *testing.T) {
+	devfile := workspaceApi.DevFileSpec{
+		Attributes: map[string]string{
+			workspaceApi.PersistVolumesAttribute: "false",
+		},
+	}
+	if !isEphemeralStorage(devfile) {
+		t.Fatal("Expected the storage to be ephemeral when persistVolumes is false")
+	}
+	volume := workspaceVolume(workspaceProperties{ephemeralStorage: true})
+	if volume.EmptyDir == nil || volume.PersistentVolumeClaim != nil {
+		t.Errorf("Expected an emptyDir workspace volume, got %+v", volume.VolumeSource)
+	}
+
+	if isEphemeralStorage(workspaceApi.DevFileSpec{}) {
+		t.Fatal("Expected the storage to be persistent by default")
+	}
+	volume = workspaceVolume(workspaceProperties{})
+	if volume.PersistentVolumeClaim == nil || volume.PersistentVolumeClaim.ClaimName != "claim-che-workspace" {
+		t.Errorf("Expected the workspace persistent volume claim, got %+v", volume.VolumeSource)
+	}
+}
+
+func TestMountSourcesDefaults(t *testing.T) {
+	for componentType, expected := range map[workspaceApi.DevfileName]bool{
+		workspaceApi.Dockerimage: false,
+		workspaceApi.Kubernetes:  true,
+		workspaceApi.Openshift:   true,
+	} {
+		mountSources := mountSourcesOrDefault(&workspaceApi.ComponentSpec{Type: componentType})
+		if *mountSources != expected {
+			t.Errorf("Expected mountSources to default to %v for %s components", expected, componentType)
+		}
+	}
+
+	disabled := false
+	mountSources := mountSourcesOrDefault(&workspaceApi.ComponentSpec{Type: workspaceApi.Kubernetes, MountSources: &disabled})
+	if *mountSources {
+		t.Error("Expected an explicit mountSources to override the default")
+	}
+}
+
+func TestMountSourcesWithEphemeralStorage(t *testing.T) {
+	ephemeral := workspaceProperties{ephemeralStorage: true}
+	mountSources, err := k8sLikeMountSources(ephemeral, &workspaceApi.ComponentSpec{Type: workspaceApi.Kubernetes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *mountSources {
+		t.Error("Expected the sources not to be mounted by default to component pods when the volumes are ephemeral")
+	}
+
+	enabled := true
+	alias := "db"
+	_, err = k8sLikeMountSources(ephemeral, &workspaceApi.ComponentSpec{Type: workspaceApi.Kubernetes, Alias: &alias, MountSources: &enabled})
+	if err == nil {
+		t.Error("Expected mountSources to be rejected on component pods when the volumes are ephemeral")
+	}
+
+	mountSources, err = k8sLikeMountSources(workspaceProperties{}, &workspaceApi.ComponentSpec{Type: workspaceApi.Kubernetes})
+	if err != nil || !*mountSources {
+		t.Error("Expected the sources to be mounted to component pods when the volumes are persisted")
+	}
+}
+
+func TestFlattenDevfileParentsAppliesDevfileChangesToCachedParent(t *testing.T) {
+	parentAlias, childAlias, image := "tools", "db", "quay.io/eclipse/che-dev:nightly"
+	parent := workspaceApi.DevFileSpec{
+		ApiVersion: workspaceApi.DevfileApiVersion,
+		Components: []workspaceApi.ComponentSpec{{Type: workspaceApi.Dockerimage, Alias: &parentAlias, Image: &image}},
+	}
+	parentYaml, err := yaml.Marshal(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &workspaceApi.DevfileSource{Url: "https://devfiles.example.com/parent.yaml"}
+	workspace := &workspaceApi.Workspace{}
+	workspace.Spec.Started = true
+	workspace.Spec.Devfile.Parent = source
+	workspace.Spec.Devfile.Components = []workspaceApi.ComponentSpec{{Type: workspaceApi.Dockerimage, Alias: &childAlias, Image: &image}}
+	workspace.Status.Phase = workspaceApi.WorkspacePhaseRunning
+	workspace.Status.FlattenedDevfileParent = string(parentYaml)
+	workspace.Status.FlattenedDevfileParentSource = `{"url":"https://devfiles.example.com/parent.yaml"}`
+
+	// The cached parent is used while the workspace runs, so no client is needed
+	if err := flattenDevfileParents(nil, workspace); err != nil {
+		t.Fatal(err)
+	}
+	components := workspace.Spec.Devfile.Components
+	if len(components) != 2 || *components[0].Alias != parentAlias || *components[1].Alias != childAlias {
+		t.Errorf("Expected the components of the devfile to be merged into the cached parent, got %v", components)
+	}
+	if workspace.Spec.Devfile.Parent != nil || workspace.Spec.Devfile.ApiVersion != workspaceApi.DevfileApiVersion {
+		t.Errorf("Expected the flattened devfile to inherit the parent apiVersion, and to have no parent")
+	}
+}
@@ -1,7 +1,6 @@
 package workspace
 
 import (
-	"strings"
 	"github.com/eclipse/che-plugin-broker/model"
 	"regexp"
 	"strconv"
@@ -13,7 +12,7 @@ import (
 	k8sModelUtils "github.com/che-incubator/che-workspace-crd-operator/pkg/controller/modelutils/k8s"
 )
 
-func setupDockerImageComponent(names workspaceProperties, commands []workspaceApi.CommandSpec, component *workspaceApi.ComponentSpec) (*ComponentInstanceStatus, error) {
+func setupDockerImageComponent(names workspaceProperties, component *workspaceApi.ComponentSpec) (*ComponentInstanceStatus, error) {
 	componentInstanceStatus := &ComponentInstanceStatus{
 		Machines: map[string]MachineDescription{},
 		Endpoints: []workspaceApi.Endpoint {},
@@ -36,7 +35,7 @@ func setupDockerImageComponent(names workspaceProperties, commands []workspaceAp
 
 	var limitOrDefault string
 
-	if *component.MemoryLimit == "" {
+	if component.MemoryLimit == nil || *component.MemoryLimit == "" {
 		limitOrDefault = "128M"
 	} else {
 		limitOrDefault = *component.MemoryLimit
@@ -47,15 +46,9 @@ func setupDockerImageComponent(names workspaceProperties, commands []workspaceAp
 		return nil, err
 	}
 
-	volumeMounts := createVolumeMounts(names, component.MountSources, component.Volumes, []model.Volume{})
+	volumeMounts := createVolumeMounts(names, mountSourcesOrDefault(component), component.Volumes, []model.Volume{})
 
-	var envVars []corev1.EnvVar
-	for _, envVarDef := range component.Env {
-		envVars = append(envVars, corev1.EnvVar{
-			Name:  envVarDef.Name,
-			Value: strings.ReplaceAll(envVarDef.Value, "$(CHE_PROJECTS_ROOT)", "/projects"),
-		})
-	}
+	envVars := componentEnvVars(component)
 	envVars = append(envVars, corev1.EnvVar{
 		Name:  "CHE_MACHINE_NAME",
 		Value: machineName,
@@ -75,6 +68,7 @@ func setupDockerImageComponent(names workspaceProperties, commands []workspaceAp
 		},
 		VolumeMounts: volumeMounts,
 		Env:          append(envVars, commonEnvironmentVariables(names)...),
+		EnvFrom:      componentEnvFrom(component),
 	}
 	if component.Command != nil {
 		container.Command = *component.Command
@@ -103,35 +97,6 @@ func setupDockerImageComponent(names workspaceProperties, commands []workspaceAp
 		MachineAttributes: machineAttributes,
     Ports: exposedPorts,
 	}
-	
-	for _, command := range commands {
-		if len(command.Actions) == 0 {
-			continue
-		}
-		action := command.Actions[0]
-		if component.Alias == nil ||
-			action.Component == nil ||
-		  *action.Component != *component.Alias {
-			continue
-		}
-		attributes := map[string]string{
-			COMMAND_WORKING_DIRECTORY_ATTRIBUTE: interpolate(emptyIfNil(action.Workdir), names),
-			COMMAND_ACTION_REFERENCE_ATTRIBUTE:  emptyIfNil(action.Reference),
-			COMMAND_ACTION_REFERENCE_CONTENT_ATTRIBUTE:  emptyIfNil(action.ReferenceContent),
-			COMMAND_MACHINE_NAME_ATTRIBUTE:      machineName,
-			COMPONENT_ALIAS_COMMAND_ATTRIBUTE:   *action.Component,
-		}
-		for attrName, attrValue := range command.Attributes {
-			attributes[attrName] = attrValue
-		}
-		componentInstanceStatus.ContributedRuntimeCommands = append(componentInstanceStatus.ContributedRuntimeCommands,
-			CheWorkspaceCommand{
-				Name:        command.Name,
-				CommandLine: emptyIfNil(action.Command),
-				Type:        action.Type,
-				Attributes: attributes,
-			})
-	}
 
 	return componentInstanceStatus, nil
 }
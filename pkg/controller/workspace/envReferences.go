@@ -0,0 +1,106 @@
+package workspace
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Period after which a workspace whose env references missing objects is reconciled again,
+// so that it starts once the objects are created
+const missingReferencesRetryPeriod = 30 * time.Second
+
+// missingEnvReferences returns the Secrets, ConfigMaps and keys referenced by the env of the workspace components
+// that don't exist in the workspace namespace. Without them, the workspace containers could not start.
+func missingEnvReferences(clt client.Client, workspace *workspaceApi.Workspace) ([]string, error) {
+	secrets := map[string]*corev1.Secret{}
+	configMaps := map[string]*corev1.ConfigMap{}
+	missing := map[string]bool{}
+
+	secretKeys := func(name string) (map[string][]byte, bool, error) {
+		secret, fetched := secrets[name]
+		if !fetched {
+			secret = &corev1.Secret{}
+			err := clt.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: workspace.Namespace}, secret)
+			if errors.IsNotFound(err) {
+				secret = nil
+			} else if err != nil {
+				return nil, false, err
+			}
+			secrets[name] = secret
+		}
+		if secret == nil {
+			return nil, false, nil
+		}
+		return secret.Data, true, nil
+	}
+	configMapKeys := func(name string) (map[string]string, bool, error) {
+		configMap, fetched := configMaps[name]
+		if !fetched {
+			configMap = &corev1.ConfigMap{}
+			err := clt.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: workspace.Namespace}, configMap)
+			if errors.IsNotFound(err) {
+				configMap = nil
+			} else if err != nil {
+				return nil, false, err
+			}
+			configMaps[name] = configMap
+		}
+		if configMap == nil {
+			return nil, false, nil
+		}
+		return configMap.Data, true, nil
+	}
+
+	for _, component := range workspace.Spec.Devfile.Components {
+		for _, env := range component.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				data, exists, err := secretKeys(ref.Name)
+				if err != nil {
+					return nil, err
+				}
+				if !exists {
+					missing["Secret '"+ref.Name+"'"] = true
+				} else if _, hasKey := data[ref.Key]; !hasKey {
+					missing["key '"+ref.Key+"' of Secret '"+ref.Name+"'"] = true
+				}
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				data, exists, err := configMapKeys(ref.Name)
+				if err != nil {
+					return nil, err
+				}
+				if !exists {
+					missing["ConfigMap '"+ref.Name+"'"] = true
+				} else if _, hasKey := data[ref.Key]; !hasKey {
+					missing["key '"+ref.Key+"' of ConfigMap '"+ref.Name+"'"] = true
+				}
+			}
+		}
+		for _, envFrom := range component.EnvFrom {
+			_, exists, err := secretKeys(envFrom.SecretName)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				missing["Secret '"+envFrom.SecretName+"'"] = true
+			}
+		}
+	}
+
+	missingReferences := []string{}
+	for reference := range missing {
+		missingReferences = append(missingReferences, reference)
+	}
+	sort.Strings(missingReferences)
+	return missingReferences, nil
+}
@@ -0,0 +1,183 @@
+package workspace
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/che-incubator/che-workspace-crd-operator/pkg/controller/registry"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Path under which the controller serves the extensions that workspace pods cannot download by themselves
+const extensionsServerPath = "/extensions/"
+
+// extensionsServer is the HTTP server of the plugin extensions, run by the manager.
+//
+// The plugins copy init container only runs curl, so it can neither read the files of a local registry,
+// which are only available to the controller, nor send the headers of a private registry.
+// Such extensions are downloaded by the controller, through its download cache, and served
+// to the init container by the extensions server.
+// Extensions are served at a path that contains a token derived from a secret key and, for private registries,
+// from the registry headers, so that only the workspaces the controller provided with the path can download them.
+type extensionsServer struct {
+	port int32
+}
+
+var ExtensionsServerKeyEnvVar = "EXTENSIONS_SERVER_KEY"
+
+// Secret key of the tokens of the extensions server paths
+var extensionsServerKey []byte
+
+// SetupExtensionsServerKey initializes the secret key of the tokens of the extensions server paths.
+// The key is set by the `EXTENSIONS_SERVER_KEY` environment variable, so that the paths, and thus the workspace pods,
+// don't change when the controller restarts. Otherwise a random key is generated.
+func SetupExtensionsServerKey() error {
+	if key, found := os.LookupEnv(ExtensionsServerKeyEnvVar); found && key != "" {
+		extensionsServerKey = []byte(key)
+		return nil
+	}
+	extensionsServerKey = make([]byte, 32)
+	_, err := rand.Read(extensionsServerKey)
+	return err
+}
+
+// NewExtensionsServer returns the server of the plugin extensions of local and private registries
+func NewExtensionsServer(port int32) manager.Runnable {
+	return &extensionsServer{port: port}
+}
+
+func (s *extensionsServer) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(extensionsServerPath, serveExtension)
+	server := &http.Server{
+		Addr:    ":" + strconv.Itoa(int(s.port)),
+		Handler: mux,
+	}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	log.Info("Serving plugin extensions on port " + strconv.Itoa(int(s.port)))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// extensionDownloadUrl returns the URL from which the plugins copy init container downloads an extension:
+// the extensions server for the extensions of local and private registries, and the extension URL otherwise.
+func extensionDownloadUrl(extensionUrl string) string {
+	if registry.ExtensionsServerUrl == "" {
+		return extensionUrl
+	}
+	token, served := extensionToken(extensionUrl)
+	if !served {
+		return extensionUrl
+	}
+	return registry.ExtensionsServerUrl + extensionsServerPath + token + "/" + url.PathEscape(extensionFileName(extensionUrl)) +
+		"?url=" + url.QueryEscape(extensionUrl)
+}
+
+// extensionFileName returns the name of the file an extension URL points to
+func extensionFileName(extensionUrl string) string {
+	fileName := path.Base(strings.SplitN(strings.SplitN(extensionUrl, "#", 2)[0], "?", 2)[0])
+	if fileName == "." || fileName == ".." || fileName == "/" {
+		return "extension"
+	}
+	return fileName
+}
+
+// extensionToken returns the token of the path at which an extension is served,
+// or false if the extension is not served by the controller.
+func extensionToken(extensionUrl string) (string, bool) {
+	if isLocalRegistry(extensionUrl) {
+		if _, inRegistry := localRegistryFile(extensionUrl); !inRegistry {
+			return "", false
+		}
+		return hmacToken(extensionsServerKey, extensionUrl), true
+	}
+	headers := controllerConfig.getPluginRegistryHeaders(extensionUrl)
+	if headers == nil {
+		return "", false
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := []string{string(extensionsServerKey)}
+	for _, name := range names {
+		key = append(key, name+": "+strings.Join(headers[name], ", "))
+	}
+	return hmacToken([]byte(strings.Join(key, "\n")), extensionUrl), true
+}
+
+func hmacToken(key []byte, extensionUrl string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(extensionUrl))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// localRegistryFile returns the file of an extension URL of a local registry, and whether this file
+// is inside one of the local registries of the controller configuration.
+func localRegistryFile(extensionUrl string) (string, bool) {
+	file := filepath.Clean(strings.TrimPrefix(extensionUrl, localRegistryPrefix))
+	for _, registryUrl := range controllerConfig.getPluginRegistries() {
+		if !isLocalRegistry(registryUrl) {
+			continue
+		}
+		registryDir := filepath.Clean(strings.TrimPrefix(registryUrl, localRegistryPrefix))
+		if strings.HasPrefix(file, registryDir+string(filepath.Separator)) {
+			return file, true
+		}
+	}
+	return file, false
+}
+
+// serveExtension serves an extension at `/extensions/{token}/{fileName}?url={extension URL}`
+func serveExtension(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.SplitN(strings.TrimPrefix(r.URL.Path, extensionsServerPath), "/", 2)
+	extensionUrl := r.URL.Query().Get("url")
+	token, served := extensionToken(extensionUrl)
+	if !served || !hmac.Equal([]byte(token), []byte(pathParts[0])) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var file *os.File
+	var err error
+	if isLocalRegistry(extensionUrl) {
+		fileName, _ := localRegistryFile(extensionUrl)
+		file, err = os.Open(fileName)
+		if err == nil {
+			if info, statErr := file.Stat(); statErr != nil || info.IsDir() {
+				file.Close()
+				http.NotFound(w, r)
+				return
+			}
+		}
+	} else {
+		file, _, err = downloadCache.open(extensionUrl, controllerConfig.getPluginRegistryHeaders(extensionUrl), extensionFileName(extensionUrl), false)
+	}
+	if err != nil {
+		log.Error(err, "Cannot provide the plugin extension", "url", extensionUrl)
+		http.Error(w, "Cannot provide the plugin extension", http.StatusBadGateway)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(w, r, extensionFileName(extensionUrl), time.Time{}, file)
+}
@@ -0,0 +1,296 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"path"
+	"strings"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Directory where the SSH keys of the workspace user are mounted.
+// The home directory differs from one container image to the other, so the keys are mounted in a fixed directory,
+// and the system-wide SSH client configuration points to them.
+const sshKeysDir = "/etc/ssh/workspace-keys"
+
+// Writable directory where the SSH client keeps the keys of the hosts it connected to,
+// when the SSH keys Secret has no known hosts
+const sshKnownHostsDir = "/etc/ssh/workspace-known-hosts"
+
+// Keys of the SSH keys Secret
+const (
+	sshPrivateKeyKey = "id_rsa"
+	sshPublicKeyKey  = "id_rsa.pub"
+	sshKnownHostsKey = "known_hosts"
+)
+
+// Size of the generated SSH keys
+const sshKeyBits = 4096
+
+// Keys of the user profile Secret
+const (
+	userProfileNameKey  = "name"
+	userProfileEmailKey = "email"
+)
+
+// Suffixes of the names of the Secrets that contain the SSH keys and the profile of a user
+const (
+	sshKeysSecretSuffix     = "-ssh-keys"
+	userProfileSecretSuffix = "-user-profile"
+)
+
+// userSecretsPrefix returns the prefix of the Secrets of the user the workspace belongs to:
+// the value of the `org.eclipse.che.workspace/user` annotation, or `workspace` for the Secrets
+// shared by all the workspaces of the namespace.
+func userSecretsPrefix(workspace *workspaceApi.Workspace) string {
+	if user := workspace.Annotations[USER_ANNOTATION]; user != "" {
+		return user
+	}
+	return "workspace"
+}
+
+func isUserSecretName(name string) bool {
+	return strings.HasSuffix(name, sshKeysSecretSuffix) || strings.HasSuffix(name, userProfileSecretSuffix)
+}
+
+// provisionGitCredentials adds to the resources mounted to the workspace containers the SSH keys and the git
+// configuration of the workspace user:
+//   - the `{user}-ssh-keys` Secret, generated with a new keypair when it doesn't exist, unless the
+//     `git.ssh.keys.generate` property is `false`, is mounted in the `/etc/ssh/workspace-keys` directory,
+//     and used by the SSH client for all hosts. Its files are readable by the filesystem group of the security profile,
+//     or by all the users of the containers when there is none.
+//   - host keys are checked against the `known_hosts` key of the SSH keys Secret. Otherwise the keys of new hosts
+//     are accepted and kept in a writable known hosts file, which requires OpenSSH 7.6 or later in the container images.
+//   - `/etc/gitconfig` sets the git `user.name` and `user.email` from the `name` and `email` keys of
+//     the optional `{user}-user-profile` Secret, and the `store` credential helpers of the git credentials files.
+//
+// This way projects are cloned from private repositories when the workspace starts.
+// No keypair is generated when the workspace objects are only rendered.
+// It returns the ConfigMap that contains the generated configuration files.
+func provisionGitCredentials(clt client.Client, workspace *workspaceApi.Workspace, wkspProps workspaceProperties, gitCredentialFiles []string, resources *automountResources, versionsHash io.Writer) (runtime.Object, error) {
+	prefix := userSecretsPrefix(workspace)
+	generateKeys := controllerConfig.isSSHKeysGenerationEnabled()
+	sshKeys, err := getOrGenerateSSHKeys(clt, workspace.Namespace, prefix+sshKeysSecretSuffix, generateKeys && !wkspProps.rendering)
+	if err != nil {
+		return nil, err
+	}
+	if sshKeys == nil && generateKeys {
+		// Rendered workspaces reference the Secret that is generated when the workspace starts
+		sshKeys = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: prefix + sshKeysSecretSuffix, Namespace: workspace.Namespace}}
+	}
+	if sshKeys != nil {
+		io.WriteString(versionsHash, "Secret/"+sshKeys.Name+"/"+sshKeys.ResourceVersion+"\n")
+	}
+
+	userProfile := &corev1.Secret{}
+	err = clt.Get(context.TODO(), types.NamespacedName{Name: prefix + userProfileSecretSuffix, Namespace: workspace.Namespace}, userProfile)
+	if k8sErrors.IsNotFound(err) {
+		userProfile = nil
+	} else if err != nil {
+		return nil, err
+	} else {
+		io.WriteString(versionsHash, "Secret/"+userProfile.Name+"/"+userProfile.ResourceVersion+"\n")
+	}
+
+	sshConfig := "Host *\n"
+	hasKnownHosts := false
+	if sshKeys != nil {
+		sshConfig += "    IdentityFile " + path.Join(sshKeysDir, sshPrivateKeyKey) + "\n"
+		_, hasKnownHosts = sshKeys.Data[sshKnownHostsKey]
+	}
+	if hasKnownHosts {
+		sshConfig += "    GlobalKnownHostsFile " + path.Join(sshKeysDir, sshKnownHostsKey) + "\n"
+		sshConfig += "    StrictHostKeyChecking yes\n"
+	} else {
+		sshConfig += "    StrictHostKeyChecking accept-new\n"
+		sshConfig += "    UserKnownHostsFile " + path.Join(sshKnownHostsDir, sshKnownHostsKey) + "\n"
+	}
+
+	gitconfig := ""
+	if userProfile != nil {
+		name, email := string(userProfile.Data[userProfileNameKey]), string(userProfile.Data[userProfileEmailKey])
+		if name != "" || email != "" {
+			gitconfig += "[user]\n"
+			if name != "" {
+				gitconfig += "\tname = " + gitConfigValue(name) + "\n"
+			}
+			if email != "" {
+				gitconfig += "\temail = " + gitConfigValue(email) + "\n"
+			}
+		}
+	}
+	if len(gitCredentialFiles) > 0 {
+		gitconfig += "[credential]\n"
+		for _, credentialsFile := range gitCredentialFiles {
+			gitconfig += "\thelper = " + gitConfigValue("store --file "+credentialsFile) + "\n"
+		}
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      wkspProps.workspaceId + "-git-config",
+			Namespace: workspace.Namespace,
+		},
+		Data: map[string]string{
+			"ssh_config": sshConfig,
+			"gitconfig":  gitconfig,
+		},
+	}
+	io.WriteString(versionsHash, sshConfig+gitconfig)
+
+	if sshKeys != nil {
+		sshKeysMode := sshKeysFileMode(controllerConfig.getSecurityProfile())
+		resources.volumes = append(resources.volumes, corev1.Volume{
+			Name: "git-ssh-keys",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: sshKeys.Name, DefaultMode: &sshKeysMode},
+			},
+		})
+		resources.volumeMounts = append(resources.volumeMounts, corev1.VolumeMount{
+			Name:      "git-ssh-keys",
+			MountPath: sshKeysDir,
+			ReadOnly:  true,
+		})
+	}
+	resources.volumes = append(resources.volumes,
+		corev1.Volume{
+			Name: "git-ssh-known-hosts",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		corev1.Volume{
+			Name: "git-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name}},
+			},
+		})
+	resources.volumeMounts = append(resources.volumeMounts,
+		corev1.VolumeMount{
+			Name:      "git-ssh-known-hosts",
+			MountPath: sshKnownHostsDir,
+		},
+		corev1.VolumeMount{
+			Name:      "git-config",
+			MountPath: "/etc/ssh/ssh_config",
+			SubPath:   "ssh_config",
+			ReadOnly:  true,
+		})
+	if gitconfig != "" {
+		resources.volumeMounts = append(resources.volumeMounts, corev1.VolumeMount{
+			Name:      "git-config",
+			MountPath: "/etc/gitconfig",
+			SubPath:   "gitconfig",
+			ReadOnly:  true,
+		})
+	}
+	return configMap, nil
+}
+
+// getOrGenerateSSHKeys returns the SSH keys Secret with the given name, after creating it with a new keypair
+// if it doesn't exist and generate is true, or nil otherwise. The Secret is not owned by the workspace,
+// so that the keypair registered on the git servers is kept when the workspace is deleted.
+func getOrGenerateSSHKeys(clt client.Client, namespace string, name string, generate bool) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := clt.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	if err == nil {
+		return secret, nil
+	}
+	if !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+	if !generate {
+		return nil, nil
+	}
+
+	log.Info("Generating the SSH keys Secret '" + name + "' in namespace '" + namespace + "'")
+	data, err := generateSSHKeys()
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	err = clt.Create(context.TODO(), secret)
+	if k8sErrors.IsAlreadyExists(err) {
+		// Created meanwhile by the reconcile of another workspace
+		secret = &corev1.Secret{}
+		err = clt.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// sshKeysFileMode returns the mode of the files of the SSH keys Secret. The files belong to root, so they are
+// readable by the filesystem group of the security profile, or by everyone when the users of the containers are unknown.
+// The SSH client accepts such private keys as long as the containers don't run as root, since they belong to another user.
+func sshKeysFileMode(profile securityProfile) int32 {
+	if profile.fsGroup != nil {
+		return 0440
+	}
+	return 0444
+}
+
+// generateSSHKeys generates an RSA keypair, with the private key in the PEM format and the public key
+// in the OpenSSH `authorized_keys` format, to be registered on the git servers
+func generateSSHKeys() (map[string][]byte, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, sshKeyBits)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		sshPrivateKeyKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}),
+		sshPublicKeyKey: sshRSAPublicKey(&privateKey.PublicKey),
+	}, nil
+}
+
+// sshRSAPublicKey encodes an RSA public key in the OpenSSH `authorized_keys` format, as described in RFC 4253
+func sshRSAPublicKey(key *rsa.PublicKey) []byte {
+	encoded := &bytes.Buffer{}
+	writeString := func(data []byte) {
+		binary.Write(encoded, binary.BigEndian, uint32(len(data)))
+		encoded.Write(data)
+	}
+	writeMpint := func(n *big.Int) {
+		data := n.Bytes()
+		if len(data) > 0 && data[0]&0x80 != 0 {
+			data = append([]byte{0}, data...)
+		}
+		writeString(data)
+	}
+	writeString([]byte("ssh-rsa"))
+	writeMpint(big.NewInt(int64(key.E)))
+	writeMpint(key.N)
+	return []byte("ssh-rsa " + base64.StdEncoding.EncodeToString(encoded.Bytes()) + "\n")
+}
+
+// gitConfigValue quotes a value of a git configuration file
+func gitConfigValue(value string) string {
+	value = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+	return "\"" + value + "\""
+}
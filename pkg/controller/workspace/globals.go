@@ -1,6 +1,8 @@
 package workspace
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -13,4 +15,11 @@ var (
 	sidecarDefaultMemoryLimit = "128M"
 	pvcStorageSize            = "1Gi"
 	cheVersion                = "7.1.0"
+
+	defaultEditor                 = "eclipse/che-theia/" + cheVersion
+	defaultPlugins                = "eclipse/che-machine-exec-plugin/" + cheVersion
+	defaultPluginRegistryCacheTTL = 10 * time.Minute
+	defaultPluginsCacheMaxSize    = "256Mi"
+	defaultSecurityUserId         = "1724"
+	defaultRecipeAllowedKinds     = "v1/Service,v1/ConfigMap,v1/Secret,v1/PersistentVolumeClaim,apps/v1/Deployment,apps/v1/StatefulSet,batch/v1/Job,extensions/v1beta1/Ingress,route.openshift.io/v1/Route"
 )
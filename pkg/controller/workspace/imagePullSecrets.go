@@ -0,0 +1,91 @@
+package workspace
+
+import (
+	"strings"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// workspaceImagePullSecrets returns the Secrets used to pull the images of the workspace:
+// the ones of the controller configuration, followed by the ones of the workspace `imagePullSecrets`
+func workspaceImagePullSecrets(workspace *workspaceApi.Workspace) []corev1.LocalObjectReference {
+	return imagePullSecretReferences(append(controllerConfig.getImagePullSecrets(), workspace.Spec.ImagePullSecrets...))
+}
+
+func imagePullSecretReferences(names []string) []corev1.LocalObjectReference {
+	pullSecrets := []corev1.LocalObjectReference{}
+	for _, name := range names {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: name})
+	}
+	return pullSecrets
+}
+
+// mergeImagePullSecrets returns the desired pull secrets, followed by the existing ones that the controller
+// didn't add, such as the `{serviceAccount}-dockercfg-*` Secrets that OpenShift adds to the ServiceAccounts.
+// The existing pull secrets added by the controller that are not desired anymore are removed.
+func mergeImagePullSecrets(desired []corev1.LocalObjectReference, existing []corev1.LocalObjectReference, previouslyAdded []string) []corev1.LocalObjectReference {
+	merged := []corev1.LocalObjectReference{}
+	// Names that are skipped: the ones already merged and the ones the controller doesn't add anymore
+	names := map[string]bool{}
+	for _, name := range previouslyAdded {
+		names[name] = true
+	}
+	for _, pullSecret := range desired {
+		names[pullSecret.Name] = false
+	}
+	for _, pullSecret := range append(append([]corev1.LocalObjectReference{}, desired...), existing...) {
+		if !names[pullSecret.Name] {
+			names[pullSecret.Name] = true
+			merged = append(merged, pullSecret)
+		}
+	}
+	return merged
+}
+
+// addedImagePullSecrets returns the names of the pull secrets that the controller added to the ServiceAccount
+func addedImagePullSecrets(serviceAccount *corev1.ServiceAccount) []string {
+	annotation := serviceAccount.Annotations[IMAGE_PULL_SECRETS_ANNOTATION]
+	if annotation == "" {
+		return []string{}
+	}
+	return strings.Split(annotation, ",")
+}
+
+// setAddedImagePullSecrets records the names of the pull secrets that the controller adds to the ServiceAccount
+func setAddedImagePullSecrets(serviceAccount *corev1.ServiceAccount) {
+	names := []string{}
+	for _, pullSecret := range serviceAccount.ImagePullSecrets {
+		names = append(names, pullSecret.Name)
+	}
+	if serviceAccount.Annotations == nil {
+		serviceAccount.Annotations = map[string]string{}
+	}
+	serviceAccount.Annotations[IMAGE_PULL_SECRETS_ANNOTATION] = strings.Join(names, ",")
+}
+
+// addImagePullSecrets adds the pull secrets to the pods of the workspace: the main workspace pod,
+// whose init containers copy the plugins of the broker, and the pods of the kubernetes and openshift components.
+// Pull secrets already set on a pod are kept, and not duplicated.
+func addImagePullSecrets(pullSecrets []corev1.LocalObjectReference, k8sObjects []runtime.Object) {
+	if len(pullSecrets) == 0 {
+		return
+	}
+	for _, obj := range k8sObjects {
+		podTemplate, _ := k8sLikePodTemplate(obj)
+		if podTemplate == nil {
+			continue
+		}
+		existing := map[string]bool{}
+		for _, pullSecret := range podTemplate.Spec.ImagePullSecrets {
+			existing[pullSecret.Name] = true
+		}
+		for _, pullSecret := range pullSecrets {
+			if !existing[pullSecret.Name] {
+				existing[pullSecret.Name] = true
+				podTemplate.Spec.ImagePullSecrets = append(podTemplate.Spec.ImagePullSecrets, pullSecret)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package workspace
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMergeImagePullSecrets(t *testing.T) {
+	desired := imagePullSecretReferences([]string{"registry-a", "registry-c"})
+	existing := imagePullSecretReferences([]string{"workspace-dockercfg-x1", "registry-a", "registry-b"})
+
+	merged := mergeImagePullSecrets(desired, existing, []string{"registry-a", "registry-b"})
+
+	expected := imagePullSecretReferences([]string{"registry-a", "registry-c", "workspace-dockercfg-x1"})
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected the pull secrets removed from the configuration to be removed, and the other ones to be kept: got %v, expected %v", merged, expected)
+	}
+}
+
+func TestAddedImagePullSecrets(t *testing.T) {
+	serviceAccount := &corev1.ServiceAccount{
+		ImagePullSecrets: imagePullSecretReferences([]string{"registry-a", "registry-b"}),
+	}
+	setAddedImagePullSecrets(serviceAccount)
+	if added := addedImagePullSecrets(serviceAccount); !reflect.DeepEqual(added, []string{"registry-a", "registry-b"}) {
+		t.Errorf("Expected the added pull secrets to be recorded, got %v", added)
+	}
+
+	serviceAccount.ImagePullSecrets = nil
+	setAddedImagePullSecrets(serviceAccount)
+	if added := addedImagePullSecrets(serviceAccount); len(added) != 0 {
+		t.Errorf("Expected no added pull secret, got %v", added)
+	}
+}
@@ -1,9 +1,12 @@
 package workspace
 
 import (
+	"encoding/json"
 	"strconv"
 	"errors"
 
+	"github.com/eclipse/che-plugin-broker/model"
+
 	routeV1 "github.com/openshift/api/route/v1"
 	templateV1 "github.com/openshift/api/template/v1"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -12,10 +15,14 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceApi.ComponentSpec) (*ComponentInstanceStatus, error) {
@@ -37,7 +44,7 @@ func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceAp
 		componentContent = *component.ReferenceContent
 	}
 
-	obj, _, err := decode([]byte(componentContent), nil, nil)
+	obj, err := decodeK8sLikeObject(decode, []byte(componentContent))
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +56,7 @@ func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceAp
 			if item.Object != nil {
 				objects = append(objects, item.Object)
 			} else {
-				decodedItem, _, err := decode(item.Raw, nil, nil)
+				decodedItem, err := decodeK8sLikeObject(decode, item.Raw)
 				if err != nil {
 					return nil, err
 				}
@@ -67,6 +74,10 @@ func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceAp
 	selector := labels.SelectorFromSet(component.Selector)
 	for _, obj = range objects {
 		if objMeta, isMeta := obj.(metav1.Object); isMeta {
+			if gvk, err := apiutil.GVKForObject(obj, theScheme); err != nil || !controllerConfig.isRecipeKindAllowed(gvk) {
+				log.Info("Object of kind '" + gvk.Kind + "' is not allowed in the `" + string(component.Type) + "` component: " + objMeta.GetName())
+				continue
+			}
 			if selector.Matches(labels.Set(objMeta.GetLabels())) {
 				objMeta.SetNamespace(wkspProps.namespace)
 				k8sObjects = append(k8sObjects, obj)
@@ -74,9 +85,19 @@ func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceAp
 		}
 	}
 
+	if err := applyEntrypoints(component, k8sObjects); err != nil {
+		return nil, err
+	}
+
 	var componentName string
 
-	// TODO: manage commands and args with pod name, container name, etc ...
+	componentInstanceStatus := &ComponentInstanceStatus{
+		Machines: map[string]MachineDescription{},
+		ExternalObjects: []runtime.Object {},
+		Endpoints: []workspaceApi.Endpoint {},
+		ContributedRuntimeCommands: []CheWorkspaceCommand {},
+	}
+	componentInstanceStatus.Endpoints = append(componentInstanceStatus.Endpoints, component.Endpoints...)
 
 	podCound := 0
 	for index, obj := range k8sObjects {
@@ -105,6 +126,14 @@ func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceAp
 				podLabels[labelName] = labelValue
 			}
 
+			err := setupK8sLikePodSpec(wkspProps, component, &objPod.Spec, map[string]string{
+				"che.workspace_id": wkspProps.workspaceId,
+				"che.original_name": additionalDeploymentOriginalName,
+			}, componentInstanceStatus)
+			if err != nil {
+				return nil, err
+			}
+
 			k8sObjects[index] = &appsv1.Deployment{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      workspaceDeploymentName,
@@ -139,17 +168,242 @@ func setupK8sLikeComponent(wkspProps workspaceProperties, component *workspaceAp
 					},
 				},
 			}
-		} else if objMeta, isMeta := obj.(metav1.Object); isMeta {
-			objMeta.GetLabels()["che.workspace_id"] = wkspProps.workspaceId
+			componentInstanceStatus.ExternalObjects = append(componentInstanceStatus.ExternalObjects, k8sObjects[index])
+		} else if podTemplate, podSelector := k8sLikePodTemplate(obj); podTemplate != nil {
+			if podTemplate.Labels == nil {
+				podTemplate.Labels = map[string]string{}
+			}
+			podTemplate.Labels["che.workspace_id"] = wkspProps.workspaceId
+			podSelector["che.workspace_id"] = wkspProps.workspaceId
+			podTemplate.Labels["che.workspace_name"] = wkspProps.workspaceName
+			err := setupK8sLikePodSpec(wkspProps, component, &podTemplate.Spec, podSelector, componentInstanceStatus)
+			if err != nil {
+				return nil, err
+			}
+			objMeta := obj.(metav1.Object)
+			objLabels := withWorkspaceIdLabel(objMeta.GetLabels(), wkspProps)
+			objLabels[SCALE_TO_ZERO_ON_STOP_LABEL] = "true"
+			objMeta.SetLabels(objLabels)
+			componentInstanceStatus.ExternalObjects = append(componentInstanceStatus.ExternalObjects, obj)
+		} else {
+			objMeta := obj.(metav1.Object)
+			objMeta.SetLabels(withWorkspaceIdLabel(objMeta.GetLabels(), wkspProps))
+			componentInstanceStatus.ExternalObjects = append(componentInstanceStatus.ExternalObjects, obj)
 		}
 	}
 
-	componentInstanceStatus := &ComponentInstanceStatus{
-		Machines: map[string]MachineDescription{},
-		ExternalObjects: []runtime.Object {},
-		Endpoints: []workspaceApi.Endpoint {},
-		ContributedRuntimeCommands: []CheWorkspaceCommand {},
+	return componentInstanceStatus, nil
+}
+
+// applyEntrypoints overrides the command and args of the containers matched by the entrypoints of the component.
+// A container is matched when the top-level object that contains it has the name and labels of the entrypoint,
+// and when it has the container name of the entrypoint. Unset criteria match any object or container.
+// An entrypoint that matches no container is an error, since it is most likely a typo in the devfile.
+func applyEntrypoints(component *workspaceApi.ComponentSpec, k8sObjects []runtime.Object) error {
+	for _, entrypoint := range component.Entrypoints {
+		parentSelector := labels.SelectorFromSet(entrypoint.ParentSelector)
+		matched := false
+		for _, obj := range k8sObjects {
+			podSpec := k8sLikePodSpec(obj)
+			if podSpec == nil {
+				continue
+			}
+			objMeta := obj.(metav1.Object)
+			if entrypoint.ParentName != "" && entrypoint.ParentName != objMeta.GetName() {
+				continue
+			}
+			if !parentSelector.Matches(labels.Set(objMeta.GetLabels())) {
+				continue
+			}
+			for containerIndex := range podSpec.Containers {
+				container := &podSpec.Containers[containerIndex]
+				if entrypoint.ContainerName != "" && entrypoint.ContainerName != container.Name {
+					continue
+				}
+				if entrypoint.Command != nil {
+					container.Command = *entrypoint.Command
+				}
+				if entrypoint.Args != nil {
+					container.Args = *entrypoint.Args
+				}
+				matched = true
+			}
+		}
+		if !matched {
+			entrypointJson, _ := json.Marshal(entrypoint)
+			return errors.New("The entrypoint " + string(entrypointJson) + " of the '" + emptyIfNil(component.Alias) + "' component matches no container")
+		}
 	}
+	return nil
+}
 
-	return componentInstanceStatus, nil
+// k8sLikePodSpec returns the pod spec of the Pods, Deployments and StatefulSets of a kubernetes or openshift component
+func k8sLikePodSpec(obj runtime.Object) *corev1.PodSpec {
+	if pod, isPod := obj.(*corev1.Pod); isPod {
+		return &pod.Spec
+	}
+	if podTemplate, _ := k8sLikePodTemplate(obj); podTemplate != nil {
+		return &podTemplate.Spec
+	}
+	return nil
+}
+
+// decodeK8sLikeObject decodes an object of a kubernetes or openshift component.
+// Objects whose kind is unknown to the controller are decoded as unstructured objects.
+func decodeK8sLikeObject(decode func([]byte, *schema.GroupVersionKind, runtime.Object) (runtime.Object, *schema.GroupVersionKind, error), content []byte) (runtime.Object, error) {
+	obj, _, err := decode(content, nil, nil)
+	if err == nil || !runtime.IsNotRegisteredError(err) {
+		return obj, err
+	}
+	jsonContent, err := yaml.ToJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	obj, _, err = unstructured.UnstructuredJSONScheme.Decode(jsonContent, nil, nil)
+	return obj, err
+}
+
+// k8sLikePodTemplate returns the pod template of the Deployments and StatefulSets of a kubernetes or openshift component,
+// along with the labels that select its pods.
+func k8sLikePodTemplate(obj runtime.Object) (*corev1.PodTemplateSpec, map[string]string) {
+	switch typedObj := obj.(type) {
+	case *appsv1.Deployment:
+		return &typedObj.Spec.Template, podSelectorLabels(typedObj.Spec.Selector, typedObj.Spec.Template.Labels)
+	case *appsv1.StatefulSet:
+		return &typedObj.Spec.Template, podSelectorLabels(typedObj.Spec.Selector, typedObj.Spec.Template.Labels)
+	}
+	return nil, nil
+}
+
+func podSelectorLabels(selector *metav1.LabelSelector, templateLabels map[string]string) map[string]string {
+	podSelector := map[string]string{}
+	if selector != nil && len(selector.MatchLabels) > 0 {
+		for name, value := range selector.MatchLabels {
+			podSelector[name] = value
+		}
+	} else {
+		for name, value := range templateLabels {
+			podSelector[name] = value
+		}
+	}
+	return podSelector
+}
+
+func withWorkspaceIdLabel(objLabels map[string]string, wkspProps workspaceProperties) map[string]string {
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	objLabels["che.workspace_id"] = wkspProps.workspaceId
+	return objLabels
+}
+
+// setupK8sLikePodSpec registers each container of a pod from a kubernetes or openshift component as a workspace machine.
+// Containers receive the command, args, environment and volumes of the component, along with the common workspace environment,
+// and their ports are exposed through services that select the pods with the given labels.
+// Container ports that don't match any endpoint of the component are still registered as internal endpoints.
+func setupK8sLikePodSpec(wkspProps workspaceProperties, component *workspaceApi.ComponentSpec, podSpec *corev1.PodSpec, podSelector map[string]string, componentInstanceStatus *ComponentInstanceStatus) error {
+	mountSources, err := k8sLikeMountSources(wkspProps, component)
+	if err != nil {
+		return err
+	}
+	volumeMounts := createVolumeMounts(wkspProps, mountSources, component.Volumes, []model.Volume{})
+	if len(volumeMounts) > 0 {
+		addWorkspaceClaimVolume(wkspProps, podSpec)
+	}
+
+	for containerIndex := range podSpec.Containers {
+		container := &podSpec.Containers[containerIndex]
+		machineName := container.Name
+		if _, exists := componentInstanceStatus.Machines[machineName]; exists {
+			return errors.New("Duplicate container name in the '" + emptyIfNil(component.Alias) + "' component: " + machineName)
+		}
+
+		exposedPorts := []int{}
+		for _, port := range container.Ports {
+			exposedPorts = append(exposedPorts, int(port.ContainerPort))
+			if !hasEndpointForPort(componentInstanceStatus.Endpoints, int64(port.ContainerPort)) {
+				endpointName := port.Name
+				if endpointName == "" {
+					endpointName = machineName + "-" + strconv.Itoa(int(port.ContainerPort))
+				}
+				componentInstanceStatus.Endpoints = append(componentInstanceStatus.Endpoints, workspaceApi.Endpoint{
+					Name: endpointName,
+					Port: int64(port.ContainerPort),
+					Attributes: map[string]string{
+						"public": "false",
+					},
+				})
+			}
+		}
+
+		container.Env = append(container.Env, componentEnvVars(component)...)
+		container.EnvFrom = append(container.EnvFrom, componentEnvFrom(component)...)
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "CHE_MACHINE_NAME",
+			Value: machineName,
+		})
+		container.Env = append(container.Env, commonEnvironmentVariables(wkspProps)...)
+		container.VolumeMounts = append(container.VolumeMounts, volumeMounts...)
+
+		for _, service := range createK8sServicesForPods(wkspProps, machineName, exposedPorts, podSelector) {
+			machineService := service
+			componentInstanceStatus.ExternalObjects = append(componentInstanceStatus.ExternalObjects, &machineService)
+		}
+
+		machineAttributes := map[string]string{
+			CONTAINER_SOURCE_ATTRIBUTE: RECIPE_CONTAINER_SOURCE,
+		}
+		if limit, exists := container.Resources.Limits[corev1.ResourceMemory]; exists {
+			if limitAsInt64, canBeConverted := limit.AsInt64(); canBeConverted {
+				machineAttributes[MEMORY_LIMIT_ATTRIBUTE] = strconv.FormatInt(limitAsInt64, 10)
+			}
+		}
+		if request, exists := container.Resources.Requests[corev1.ResourceMemory]; exists {
+			if requestAsInt64, canBeConverted := request.AsInt64(); canBeConverted {
+				machineAttributes[MEMORY_REQUEST_ATTRIBUTE] = strconv.FormatInt(requestAsInt64, 10)
+			}
+		}
+		componentInstanceStatus.Machines[machineName] = MachineDescription{
+			MachineAttributes: machineAttributes,
+			Ports:             exposedPorts,
+		}
+	}
+	return nil
+}
+
+func hasEndpointForPort(endpoints []workspaceApi.Endpoint, port int64) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+// k8sLikeMountSources returns whether the project sources are mounted to the pods of a kubernetes or openshift component.
+// When the workspace volumes are not persisted, the sources live in an emptyDir volume of the workspace pod,
+// which the pods of the component can't share: the sources are then not mounted by default,
+// and a component that explicitly sets `mountSources` is rejected.
+// The volumes of the component are still mounted, from an emptyDir volume of its own pods.
+func k8sLikeMountSources(wkspProps workspaceProperties, component *workspaceApi.ComponentSpec) (*bool, error) {
+	mountSources := mountSourcesOrDefault(component)
+	if !wkspProps.ephemeralStorage || !*mountSources {
+		return mountSources, nil
+	}
+	if component.MountSources != nil {
+		return nil, errors.New("Component '" + emptyIfNil(component.Alias) + "' cannot mount the project sources when the workspace volumes are not persisted, " +
+			"since its pods don't share the ephemeral volume of the workspace pod")
+	}
+	noSources := false
+	return &noSources, nil
+}
+
+// addWorkspaceClaimVolume adds the workspace volume to a pod that doesn't have it yet
+func addWorkspaceClaimVolume(wkspProps workspaceProperties, podSpec *corev1.PodSpec) {
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == "claim-che-workspace" {
+			return
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, workspaceVolume(wkspProps))
 }
@@ -15,10 +15,10 @@ func managePrerequisites(workspace *workspaceApi.Workspace) ([]runtime.Object, e
 		return nil, err
 	}
 
-	autoMountServiceAccount := true
-
-	k8sObjects := []runtime.Object{
-		&corev1.PersistentVolumeClaim{
+	k8sObjects := []runtime.Object{}
+	// Workspaces with ephemeral storage don't use the workspace persistent volume claim
+	if !isEphemeralStorage(workspace.Spec.Devfile) {
+		k8sObjects = append(k8sObjects, &corev1.PersistentVolumeClaim{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "claim-che-workspace",
 				Namespace: workspace.Namespace,
@@ -34,91 +34,153 @@ func managePrerequisites(workspace *workspaceApi.Workspace) ([]runtime.Object, e
 				},
 				StorageClassName: controllerConfig.getPVCStorageClassName(),
 			},
+		})
+	}
+
+	serviceAccountName := workspaceServiceAccountName(workspace)
+	rbacProfile := controllerConfig.getRbacProfile()
+	// The token is only mounted to the che-rest-apis container, except in the full profile,
+	// where the tooling of the workspace containers uses it to access the namespace
+	autoMountServiceAccount := rbacProfile == rbacProfileFull
+	// A per-workspace ServiceAccount, with its roles and role bindings, is deleted along with the workspace
+	ownerReferences := []metav1.OwnerReference{}
+	if controllerConfig.isServiceAccountPerWorkspace() {
+		ownerReferences = append(ownerReferences, *metav1.NewControllerRef(workspace, workspaceApi.SchemeGroupVersion.WithKind("Workspace")))
+	}
+
+	// A shared ServiceAccount only gets the pull secrets of the controller configuration:
+	// the ones of the workspace are set on its pods
+	imagePullSecrets := imagePullSecretReferences(controllerConfig.getImagePullSecrets())
+	if controllerConfig.isServiceAccountPerWorkspace() {
+		imagePullSecrets = workspaceImagePullSecrets(workspace)
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            serviceAccountName,
+			Namespace:       workspace.Namespace,
+			OwnerReferences: ownerReferences,
 		},
-		&corev1.ServiceAccount{
+		AutomountServiceAccountToken: &autoMountServiceAccount,
+		ImagePullSecrets:             imagePullSecrets,
+	}
+	setAddedImagePullSecrets(serviceAccount)
+	k8sObjects = append(k8sObjects, serviceAccount)
+
+	roleBinding := func(suffix string, roleKind string, roleName string) *rbacv1.RoleBinding {
+		return &rbacv1.RoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      serviceAccount,
-				Namespace: workspace.Namespace,
+				Name:            serviceAccountName + suffix,
+				Namespace:       workspace.Namespace,
+				OwnerReferences: ownerReferences,
 			},
-			AutomountServiceAccountToken: &autoMountServiceAccount,
-		},
-		&rbacv1.Role{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "exec",
-				Namespace: workspace.Namespace,
+			RoleRef: rbacv1.RoleRef{
+				Kind: roleKind,
+				Name: roleName,
 			},
-			Rules: []rbacv1.PolicyRule{
-				rbacv1.PolicyRule{
-					Resources: []string{"pods/exec"},
-					APIGroups: []string{""},
-					Verbs:     []string{"create"},
+			Subjects: []rbacv1.Subject{
+				rbacv1.Subject{
+					Kind:      "ServiceAccount",
+					Name:      serviceAccountName,
+					Namespace: workspace.Namespace,
 				},
 			},
-		},
-		&rbacv1.Role{
+		}
+	}
+
+	switch rbacProfile {
+	case rbacProfileMinimal:
+		// che-rest-apis only needs to get the workspace. A per-workspace ServiceAccount is restricted to its own workspace.
+		role := &rbacv1.Role{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      "view-workspaces",
+				Name:      "get-workspaces",
 				Namespace: workspace.Namespace,
 			},
 			Rules: []rbacv1.PolicyRule{
 				rbacv1.PolicyRule{
 					Resources: []string{"workspaces"},
 					APIGroups: []string{"workspace.che.eclipse.org"},
-					Verbs:     []string{"get", "list"},
+					Verbs:     []string{"get"},
 				},
 			},
-		},
-		&rbacv1.RoleBinding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      serviceAccount + "-view",
-				Namespace: workspace.Namespace,
-			},
-			RoleRef: rbacv1.RoleRef{
-				Kind: "ClusterRole",
-				Name: "view",
-			},
-			Subjects: []rbacv1.Subject{
-				rbacv1.Subject{
-					Kind:      "ServiceAccount",
-					Name:      serviceAccount,
+		}
+		if controllerConfig.isServiceAccountPerWorkspace() {
+			role.Name = serviceAccountName + "-get-workspace"
+			role.OwnerReferences = ownerReferences
+			role.Rules[0].ResourceNames = []string{workspace.Name}
+		}
+		k8sObjects = append(k8sObjects,
+			role,
+			roleBinding("-get-workspaces", "Role", role.Name),
+		)
+	case rbacProfileFull:
+		k8sObjects = append(k8sObjects,
+			&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "exec",
 					Namespace: workspace.Namespace,
 				},
-			},
-		},
-		&rbacv1.RoleBinding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      serviceAccount + "-exec",
-				Namespace: workspace.Namespace,
-			},
-			RoleRef: rbacv1.RoleRef{
-				Kind: "Role",
-				Name: "exec",
-			},
-			Subjects: []rbacv1.Subject{
-				rbacv1.Subject{
-					Kind:      "ServiceAccount",
-					Name:      serviceAccount,
-					Namespace: workspace.Namespace,
+				Rules: []rbacv1.PolicyRule{
+					rbacv1.PolicyRule{
+						Resources: []string{"pods/exec"},
+						APIGroups: []string{""},
+						Verbs:     []string{"create"},
+					},
 				},
 			},
-		},
-		&rbacv1.RoleBinding{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      serviceAccount + "-view-workspaces",
-				Namespace: workspace.Namespace,
-			},
-			RoleRef: rbacv1.RoleRef{
-				Kind: "Role",
-				Name: "view-workspaces",
-			},
-			Subjects: []rbacv1.Subject{
-				rbacv1.Subject{
-					Kind:      "ServiceAccount",
-					Name:      serviceAccount,
+			&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "view-workspaces",
 					Namespace: workspace.Namespace,
 				},
+				Rules: []rbacv1.PolicyRule{
+					rbacv1.PolicyRule{
+						Resources: []string{"workspaces"},
+						APIGroups: []string{"workspace.che.eclipse.org"},
+						Verbs:     []string{"get", "list"},
+					},
+				},
 			},
-		},
+			roleBinding("-view", "ClusterRole", "view"),
+			roleBinding("-exec", "Role", "exec"),
+			roleBinding("-view-workspaces", "Role", "view-workspaces"),
+		)
 	}
 	return k8sObjects, nil
 }
+
+// obsoleteRoleBindings returns the role bindings of the workspace ServiceAccount that the RBAC profile doesn't grant,
+// and that should be deleted when the profile changes
+func obsoleteRoleBindings(workspace *workspaceApi.Workspace) []*rbacv1.RoleBinding {
+	serviceAccountName := workspaceServiceAccountName(workspace)
+	granted := map[string][]string{
+		rbacProfileNone:    []string{},
+		rbacProfileMinimal: []string{"-get-workspaces"},
+		rbacProfileFull:    []string{"-view", "-exec", "-view-workspaces"},
+	}
+	current := map[string]bool{}
+	for _, suffix := range granted[controllerConfig.getRbacProfile()] {
+		current[suffix] = true
+	}
+	obsolete := []*rbacv1.RoleBinding{}
+	for _, suffix := range []string{"-get-workspaces", "-view", "-exec", "-view-workspaces"} {
+		if !current[suffix] {
+			obsolete = append(obsolete, &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      serviceAccountName + suffix,
+					Namespace: workspace.Namespace,
+				},
+			})
+		}
+	}
+	return obsolete
+}
+
+// workspaceServiceAccountName returns the name of the ServiceAccount of the workspace pods:
+// the `che-workspace` ServiceAccount shared by the workspaces of the namespace,
+// or a ServiceAccount dedicated to the workspace when the `rbac.service.account.per.workspace` property is "true"
+func workspaceServiceAccountName(workspace *workspaceApi.Workspace) string {
+	if controllerConfig.isServiceAccountPerWorkspace() {
+		return serviceAccount + "-" + workspace.Name
+	}
+	return serviceAccount
+}
@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testWorkspace() *workspaceApi.Workspace {
+	return &workspaceApi.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "workspace1", Namespace: "che"},
+		Spec: workspaceApi.WorkspaceSpec{
+			ImagePullSecrets: []string{"workspace-registry"},
+		},
+	}
+}
+
+// prerequisiteServiceAccount returns the ServiceAccount and the names of the RoleBindings of the prerequisites
+func prerequisiteServiceAccount(t *testing.T, workspace *workspaceApi.Workspace) (*corev1.ServiceAccount, []string) {
+	prerequisites, err := managePrerequisites(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found *corev1.ServiceAccount
+	roleBindings := []string{}
+	for _, prerequisite := range prerequisites {
+		switch object := prerequisite.(type) {
+		case *corev1.ServiceAccount:
+			found = object
+		case *rbacv1.RoleBinding:
+			roleBindings = append(roleBindings, object.Name)
+		}
+	}
+	if found == nil {
+		t.Fatal("Expected a ServiceAccount in the prerequisites")
+	}
+	sort.Strings(roleBindings)
+	return found, roleBindings
+}
+
+func TestRbacProfiles(t *testing.T) {
+	for profile, expected := range map[string][]string{
+		rbacProfileNone:    {},
+		rbacProfileMinimal: {"che-workspace-get-workspaces"},
+		rbacProfileFull:    {"che-workspace-exec", "che-workspace-view", "che-workspace-view-workspaces"},
+	} {
+		SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{"rbac.profile": profile}}, false)
+		workspace := testWorkspace()
+		serviceAccount, roleBindings := prerequisiteServiceAccount(t, workspace)
+
+		if !reflect.DeepEqual(roleBindings, expected) {
+			t.Errorf("Expected the role bindings %v for the '%s' profile, got %v", expected, profile, roleBindings)
+		}
+		if *serviceAccount.AutomountServiceAccountToken != (profile == rbacProfileFull) {
+			t.Errorf("Expected the token to only be mounted to all the containers in the full profile, got %v for the '%s' profile",
+				*serviceAccount.AutomountServiceAccountToken, profile)
+		}
+
+		obsolete := []string{}
+		for _, roleBinding := range obsoleteRoleBindings(workspace) {
+			obsolete = append(obsolete, roleBinding.Name)
+		}
+		if len(obsolete)+len(roleBindings) != 4 {
+			t.Errorf("Expected the role bindings not granted by the '%s' profile to be obsolete, got %v", profile, obsolete)
+		}
+		for _, name := range obsolete {
+			for _, granted := range roleBindings {
+				if name == granted {
+					t.Errorf("Expected the role binding '%s' granted by the '%s' profile not to be obsolete", name, profile)
+				}
+			}
+		}
+	}
+}
+
+func TestCheRestApisToken(t *testing.T) {
+	for profile, expectedVolumes := range map[string]int{rbacProfileMinimal: 1, rbacProfileFull: 0} {
+		SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{"rbac.profile": profile, "ingress.global.domain": "example.com"}}, false)
+		podSpec := &corev1.PodSpec{}
+		if _, _, err := addCheRestApis(workspaceProperties{workspaceId: "workspace1"}, podSpec); err != nil {
+			t.Fatal(err)
+		}
+		if len(podSpec.Volumes) != expectedVolumes || len(podSpec.Containers[0].VolumeMounts) != expectedVolumes {
+			t.Errorf("Expected %d token volume for the '%s' profile, got %d", expectedVolumes, profile, len(podSpec.Volumes))
+		}
+	}
+
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{"rbac.profile": rbacProfileNone}}, false)
+	if _, _, err := addCheRestApis(workspaceProperties{workspaceId: "workspace1"}, &corev1.PodSpec{}); err == nil {
+		t.Error("Expected the none profile to be rejected, since che-rest-apis cannot get the workspace")
+	}
+}
+
+func TestServiceAccountImagePullSecrets(t *testing.T) {
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{"image.pull.secrets": "controller-registry"}}, false)
+	serviceAccount, _ := prerequisiteServiceAccount(t, testWorkspace())
+	if !reflect.DeepEqual(serviceAccount.ImagePullSecrets, imagePullSecretReferences([]string{"controller-registry"})) {
+		t.Errorf("Expected the shared ServiceAccount to only get the controller pull secrets, got %v", serviceAccount.ImagePullSecrets)
+	}
+	if serviceAccount.Annotations[IMAGE_PULL_SECRETS_ANNOTATION] != "controller-registry" {
+		t.Errorf("Expected the added pull secrets to be recorded, got '%s'", serviceAccount.Annotations[IMAGE_PULL_SECRETS_ANNOTATION])
+	}
+
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{
+		"image.pull.secrets":                 "controller-registry",
+		"rbac.service.account.per.workspace": "true",
+	}}, false)
+	serviceAccount, _ = prerequisiteServiceAccount(t, testWorkspace())
+	if !reflect.DeepEqual(serviceAccount.ImagePullSecrets, imagePullSecretReferences([]string{"controller-registry", "workspace-registry"})) {
+		t.Errorf("Expected the per-workspace ServiceAccount to also get the workspace pull secrets, got %v", serviceAccount.ImagePullSecrets)
+	}
+}
@@ -0,0 +1,328 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/che-plugin-broker/model"
+	"github.com/eclipse/che-plugin-broker/utils"
+)
+
+// Maximum size of the responses of private plugin registries
+const maxPluginRegistryResponseSize = 16 * 1024 * 1024
+
+var pluginRegistryHttpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// Prefix of plugin registry URLs that point to a local directory.
+// The directory is expected to follow the layout of the plugin registry:
+// `plugins/{publisher}/{name}/{version}/meta.yaml`. A ConfigMap bundle can be
+// mounted with this layout by mapping each key to its path through `items`.
+const localRegistryPrefix = "file://"
+
+type pluginMetaCacheEntry struct {
+	meta    []byte
+	expires time.Time
+}
+
+type pluginMetaCache struct {
+	entries map[string]pluginMetaCacheEntry
+	mux     sync.Mutex
+}
+
+var pluginMetas = &pluginMetaCache{
+	entries: map[string]pluginMetaCacheEntry{},
+}
+
+func isLocalRegistry(registryUrl string) bool {
+	return strings.HasPrefix(registryUrl, localRegistryPrefix)
+}
+
+// checkPluginFQN checks that a plugin, whose id and registry come from the devfile, can only be read
+// from the filesystem of the controller through the local registries of the controller configuration.
+func checkPluginFQN(pluginFQN model.PluginFQN) error {
+	for _, idPart := range strings.Split(pluginFQN.ID, "/") {
+		if idPart == "" || idPart == "." || idPart == ".." {
+			return errors.New("Invalid plugin id: " + pluginFQN.ID)
+		}
+	}
+	if pluginFQN.Registry == "" || !isLocalRegistry(pluginFQN.Registry) {
+		return nil
+	}
+	for _, registryUrl := range controllerConfig.getPluginRegistries() {
+		if strings.TrimSuffix(registryUrl, "/") == strings.TrimSuffix(pluginFQN.Registry, "/") {
+			return nil
+		}
+	}
+	return errors.New("Plugin '" + pluginFQN.ID + "' cannot be read from the local registry '" + pluginFQN.Registry + "', which is not a configured plugin registry")
+}
+
+// getPluginMeta returns the validated meta.yaml of a plugin, along with the registry it was found in.
+// If the plugin FQN contains a registry, only this registry is used. Otherwise the registries
+// configured in the controller config map are tried in order.
+func getPluginMeta(pluginFQN model.PluginFQN, ioUtil utils.IoUtil) (*model.PluginMeta, string, error) {
+	if err := checkPluginFQN(pluginFQN); err != nil {
+		return nil, "", err
+	}
+	registries := pluginRegistriesFor(pluginFQN)
+	if len(registries) == 0 {
+		return nil, "", errors.New("No plugin registry configured to resolve plugin '" + pluginFQN.ID + "'")
+	}
+
+	registryErrors := []string{}
+	for _, registryUrl := range registries {
+		registryPluginFQN := pluginFQN
+		registryPluginFQN.Registry = ""
+		pluginMeta, err := getPluginMetaFromRegistry(registryPluginFQN, registryUrl, ioUtil)
+		if err == nil {
+			return pluginMeta, registryUrl, nil
+		}
+		registryErrors = append(registryErrors, registryUrl+": "+err.Error())
+	}
+	return nil, "", fmt.Errorf("Cannot resolve plugin '%s' from the plugin registries [%s]", pluginFQN.ID, strings.Join(registryErrors, "; "))
+}
+
+// getPluginMetaFromRegistry returns the validated meta.yaml of a plugin, with relative extension paths
+// resolved against the registry.
+// Resolved metas are kept in memory for the duration configured in the controller config map,
+// so that reconciles of started workspaces don't hit the plugin registry each time.
+func getPluginMetaFromRegistry(pluginFQN model.PluginFQN, registryUrl string, ioUtil utils.IoUtil) (*model.PluginMeta, error) {
+	key := join("|", registryUrl, pluginFQN.ID)
+	if pluginMeta := pluginMetas.get(key); pluginMeta != nil {
+		return pluginMeta, nil
+	}
+
+	pluginMeta, err := utils.GetPluginMeta(pluginFQN, registryUrl, registryIoUtil(registryUrl, ioUtil))
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedMetas := []model.PluginMeta{*pluginMeta}
+	err = utils.ResolveRelativeExtensionPaths(resolvedMetas, registryUrl)
+	if err != nil {
+		return nil, err
+	}
+	err = utils.ValidateMetas(resolvedMetas...)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginMetas.put(key, resolvedMetas[0], controllerConfig.getPluginRegistryCacheTTL())
+	return &resolvedMetas[0], nil
+}
+
+// resolvePluginVersion replaces the `latest` version or a semver range in the plugin id
+// by the highest matching version found in the index of the plugin registries.
+func resolvePluginVersion(pluginFQN model.PluginFQN, ioUtil utils.IoUtil) (model.PluginFQN, error) {
+	if err := checkPluginFQN(pluginFQN); err != nil {
+		return pluginFQN, err
+	}
+	idParts := strings.Split(pluginFQN.ID, "/")
+	publisherAndName := strings.Join(idParts[:len(idParts)-1], "/")
+	constraint := idParts[len(idParts)-1]
+
+	registries := pluginRegistriesFor(pluginFQN)
+	registryErrors := []string{}
+	for _, registryUrl := range registries {
+		availableVersions, err := getPluginVersions(registryUrl, publisherAndName, ioUtil)
+		if err != nil {
+			registryErrors = append(registryErrors, registryUrl+": "+err.Error())
+			continue
+		}
+		version, err := selectPluginVersion(constraint, availableVersions)
+		if err != nil {
+			return pluginFQN, err
+		}
+		if version == "" && constraint == "latest" {
+			// The registry may only provide a `latest` alias without any released version
+			for _, available := range availableVersions {
+				if available == "latest" {
+					version = available
+				}
+			}
+		}
+		if version == "" {
+			registryErrors = append(registryErrors, registryUrl+": no matching version in "+strings.Join(availableVersions, ", "))
+			continue
+		}
+		resolvedPluginFQN := pluginFQN
+		resolvedPluginFQN.ID = publisherAndName + "/" + version
+		return resolvedPluginFQN, nil
+	}
+	return pluginFQN, fmt.Errorf("Cannot resolve version '%s' of plugin '%s' from the plugin registries [%s]", constraint, publisherAndName, strings.Join(registryErrors, "; "))
+}
+
+// getPluginVersions returns the versions of a plugin available in a registry.
+// For remote registries, they are read from the plugin index served at `{registry}/plugins/`.
+func getPluginVersions(registryUrl string, publisherAndName string, ioUtil utils.IoUtil) ([]string, error) {
+	versions := []string{}
+	if isLocalRegistry(registryUrl) {
+		pluginDir := filepath.Join(strings.TrimPrefix(registryUrl, localRegistryPrefix), "plugins", publisherAndName)
+		files, err := ioutil.ReadDir(pluginDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				versions = append(versions, file.Name())
+			}
+		}
+		return versions, nil
+	}
+
+	indexContent, err := registryIoUtil(registryUrl, ioUtil).Fetch(strings.TrimSuffix(registryUrl, "/") + "/plugins/")
+	if err != nil {
+		return nil, err
+	}
+	index := []pluginIndexEntry{}
+	err = json.Unmarshal(indexContent, &index)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range index {
+		if !strings.HasPrefix(entry.ID, publisherAndName+"/") {
+			continue
+		}
+		version := entry.Version
+		if version == "" {
+			version = strings.TrimPrefix(entry.ID, publisherAndName+"/")
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// pluginIndexEntry is an entry of the plugin index of a registry
+type pluginIndexEntry struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// pluginRegistriesFor returns the registries in which the plugin should be looked for
+func pluginRegistriesFor(pluginFQN model.PluginFQN) []string {
+	if pluginFQN.Registry != "" {
+		return []string{pluginFQN.Registry}
+	}
+	return controllerConfig.getPluginRegistries()
+}
+
+// registryIoUtil returns an IoUtil that sends the headers configured for the registry, if any
+func registryIoUtil(registryUrl string, ioUtil utils.IoUtil) utils.IoUtil {
+	if headers := controllerConfig.getPluginRegistryHeaders(registryUrl); headers != nil {
+		return &authenticatingIoUtil{
+			IoUtil:  ioUtil,
+			headers: headers,
+		}
+	}
+	return ioUtil
+}
+
+// registryHost returns the host name of a plugin registry URL,
+// which is the key used to find its headers in the registry auth secret.
+func registryHost(registryUrl string) string {
+	parsed, err := url.Parse(registryUrl)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+// parseRegistryHeaders parses HTTP header lines in the `Name: value` format.
+func parseRegistryHeaders(content string) http.Header {
+	headers := http.Header{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		nameAndValue := strings.SplitN(line, ":", 2)
+		if len(nameAndValue) != 2 {
+			log.Info("Ignoring invalid plugin registry header line")
+			continue
+		}
+		headers.Add(strings.TrimSpace(nameAndValue[0]), strings.TrimSpace(nameAndValue[1]))
+	}
+	return headers
+}
+
+// authenticatingIoUtil adds the configured headers to the requests
+// sent to a private plugin registry.
+type authenticatingIoUtil struct {
+	utils.IoUtil
+	headers http.Header
+}
+
+func (util *authenticatingIoUtil) Fetch(URL string) ([]byte, error) {
+	if isLocalRegistry(URL) {
+		return util.IoUtil.Fetch(URL)
+	}
+	request, err := http.NewRequest(http.MethodGet, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	addHeaders(request, util.headers)
+	response, err := pluginRegistryHttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Downloading %s failed. Status code %v", URL, response.StatusCode)
+	}
+	content, err := ioutil.ReadAll(&io.LimitedReader{R: response.Body, N: maxPluginRegistryResponseSize + 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxPluginRegistryResponseSize {
+		return nil, fmt.Errorf("Downloading %s failed. The response is too large", URL)
+	}
+	return content, nil
+}
+
+func (c *pluginMetaCache) get(key string) *model.PluginMeta {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	// Metas are stored serialized since the plugin conversion modifies the
+	// containers of the meta it receives.
+	pluginMeta := &model.PluginMeta{}
+	if err := json.Unmarshal(entry.meta, pluginMeta); err != nil {
+		delete(c.entries, key)
+		return nil
+	}
+	return pluginMeta
+}
+
+func (c *pluginMetaCache) put(key string, pluginMeta model.PluginMeta, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	serialized, err := json.Marshal(pluginMeta)
+	if err != nil {
+		log.Error(err, "Cannot cache the plugin meta", "plugin", key)
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.entries[key] = pluginMetaCacheEntry{
+		meta:    serialized,
+		expires: time.Now().Add(ttl),
+	}
+}
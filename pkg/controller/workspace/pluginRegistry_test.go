@@ -0,0 +1,36 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/eclipse/che-plugin-broker/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckPluginFQN(t *testing.T) {
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{
+		"plugin.registry": "file:///var/plugin-registry/, https://registry.example.com/v3",
+	}}, false)
+
+	tests := []struct {
+		pluginFQN model.PluginFQN
+		valid     bool
+	}{
+		{model.PluginFQN{ID: "eclipse/che-theia/next"}, true},
+		{model.PluginFQN{ID: "eclipse/che-theia/next", Registry: "https://other.example.com"}, true},
+		{model.PluginFQN{ID: "eclipse/che-theia/next", Registry: "file:///var/plugin-registry"}, true},
+		{model.PluginFQN{ID: "eclipse/che-theia/next", Registry: "file:///etc"}, false},
+		{model.PluginFQN{ID: "eclipse/che-theia/next", Registry: "file:///var/plugin-registry/plugins/eclipse"}, false},
+		{model.PluginFQN{ID: "../../etc"}, false},
+		{model.PluginFQN{ID: "eclipse/che-theia/..", Registry: "file:///var/plugin-registry"}, false},
+	}
+	for _, test := range tests {
+		err := checkPluginFQN(test.pluginFQN)
+		if test.valid && err != nil {
+			t.Errorf("Expected plugin %+v to be accepted: %s", test.pluginFQN, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected plugin %+v to be rejected", test.pluginFQN)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package workspace
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Version of a plugin as found in the plugin registry, with an optional pre-release suffix
+type pluginVersion struct {
+	numbers    [3]int
+	preRelease string
+	original   string
+}
+
+func parsePluginVersion(version string) (*pluginVersion, error) {
+	parsed := &pluginVersion{original: version}
+	version = strings.TrimPrefix(version, "v")
+	if dash := strings.Index(version, "-"); dash >= 0 {
+		parsed.preRelease = version[dash+1:]
+		version = version[:dash]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return nil, errors.New("Invalid plugin version: " + parsed.original)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, errors.New("Invalid plugin version: " + parsed.original)
+		}
+		parsed.numbers[i] = number
+	}
+	return parsed, nil
+}
+
+func (v *pluginVersion) compare(other *pluginVersion) int {
+	for i := range v.numbers {
+		if v.numbers[i] != other.numbers[i] {
+			if v.numbers[i] < other.numbers[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.preRelease == other.preRelease:
+		return 0
+	case v.preRelease == "":
+		return 1
+	case other.preRelease == "":
+		return -1
+	case v.preRelease < other.preRelease:
+		return -1
+	}
+	return 1
+}
+
+// A single comparison of a version constraint, such as `>=1.2.0`
+type versionComparison struct {
+	operator string
+	version  *pluginVersion
+}
+
+func (c versionComparison) matches(v *pluginVersion) bool {
+	comparison := v.compare(c.version)
+	switch c.operator {
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	}
+	return comparison == 0
+}
+
+// isPluginVersionConstraint returns true if the plugin version is not a concrete version,
+// but `latest` or a semver range such as `^0.46`, `~1.2.0`, `1.x` or `>=1.0 <2.0`.
+func isPluginVersionConstraint(version string) bool {
+	if version == "latest" || strings.ContainsAny(version, "^~<>=* ") {
+		return true
+	}
+	for _, part := range strings.Split(version, ".") {
+		if part == "x" || part == "X" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVersionConstraint converts a semver range into the list of comparisons a version must all match.
+// The `latest` constraint matches every released version.
+func parseVersionConstraint(constraint string) ([]versionComparison, error) {
+	comparisons := []versionComparison{}
+	if constraint == "latest" {
+		return comparisons, nil
+	}
+	for _, part := range strings.Fields(constraint) {
+		switch {
+		case strings.HasPrefix(part, "^"), strings.HasPrefix(part, "~"):
+			lower, partCount, err := parsePartialVersion(part[1:])
+			if err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, versionComparison{operator: ">=", version: lower})
+			if partCount == 0 {
+				continue
+			}
+			// `~` allows patch changes when the minor version is specified, and minor changes otherwise.
+			// `^` allows changes that do not modify the left-most non-zero specified part,
+			// so that `^1.2.3` allows `1.x`, `^0.2.3` allows `0.2.x` and `^0.0.3` only allows `0.0.3`.
+			bumped := 0
+			if part[0] == '~' && partCount > 1 {
+				bumped = 1
+			} else if part[0] == '^' {
+				bumped = partCount - 1
+				for i := 0; i < partCount; i++ {
+					if lower.numbers[i] != 0 {
+						bumped = i
+						break
+					}
+				}
+			}
+			upper := &pluginVersion{}
+			copy(upper.numbers[:bumped], lower.numbers[:bumped])
+			upper.numbers[bumped] = lower.numbers[bumped] + 1
+			comparisons = append(comparisons, versionComparison{operator: "<", version: upper})
+		case strings.HasPrefix(part, ">="), strings.HasPrefix(part, "<="):
+			version, err := parsePluginVersion(part[2:])
+			if err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, versionComparison{operator: part[:2], version: version})
+		case strings.HasPrefix(part, ">"), strings.HasPrefix(part, "<"):
+			version, err := parsePluginVersion(part[1:])
+			if err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, versionComparison{operator: part[:1], version: version})
+		default:
+			lower, partCount, err := parsePartialVersion(strings.TrimPrefix(part, "="))
+			if err != nil {
+				return nil, err
+			}
+			if partCount == 3 {
+				comparisons = append(comparisons, versionComparison{operator: "=", version: lower})
+				continue
+			}
+			comparisons = append(comparisons, versionComparison{operator: ">=", version: lower})
+			if partCount > 0 {
+				upper := &pluginVersion{}
+				upper.numbers = lower.numbers
+				upper.numbers[partCount-1]++
+				comparisons = append(comparisons, versionComparison{operator: "<", version: upper})
+			}
+		}
+	}
+	return comparisons, nil
+}
+
+// parsePartialVersion parses versions such as `1`, `1.2`, `1.x` or `1.2.3-beta.1`,
+// and returns the number of version parts that were specified.
+func parsePartialVersion(version string) (*pluginVersion, int, error) {
+	if strings.Contains(version, "-") {
+		parsed, err := parsePluginVersion(version)
+		if err != nil {
+			return nil, 0, err
+		}
+		return parsed, 3, nil
+	}
+	parts := strings.Split(version, ".")
+	specified := []string{}
+	for _, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		specified = append(specified, part)
+	}
+	if len(specified) == 0 {
+		return &pluginVersion{}, 0, nil
+	}
+	parsed, err := parsePluginVersion(strings.Join(specified, "."))
+	if err != nil {
+		return nil, 0, err
+	}
+	return parsed, len(specified), nil
+}
+
+// selectPluginVersion returns the highest of the available versions matching the constraint.
+// Versions that are not semver (like `next`) are ignored, as well as pre-release versions,
+// unless the constraint explicitly references a pre-release of the same version, as in `>=1.2.0-beta.1`.
+func selectPluginVersion(constraint string, availableVersions []string) (string, error) {
+	comparisons, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	var selected *pluginVersion
+	for _, available := range availableVersions {
+		version, err := parsePluginVersion(available)
+		if err != nil || (version.preRelease != "" && !allowsPreRelease(comparisons, version)) {
+			continue
+		}
+		matches := true
+		for _, comparison := range comparisons {
+			if !comparison.matches(version) {
+				matches = false
+				break
+			}
+		}
+		if matches && (selected == nil || version.compare(selected) > 0) {
+			selected = version
+		}
+	}
+	if selected == nil {
+		return "", nil
+	}
+	return selected.original, nil
+}
+
+func allowsPreRelease(comparisons []versionComparison, version *pluginVersion) bool {
+	for _, comparison := range comparisons {
+		if comparison.version.preRelease != "" && comparison.version.numbers == version.numbers {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package workspace
+
+import (
+	"testing"
+)
+
+var registryVersions = []string{
+	"0.0.1", "0.0.3", "0.0.4", "0.1.0", "0.1.5", "0.2.0",
+	"1.0.0", "1.2.0", "1.2.3", "1.2.9", "1.3.0", "1.4.0-beta.1", "1.4.0-beta.2",
+	"2.0.0-rc.1", "next",
+}
+
+func TestSelectPluginVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{"latest", "1.3.0"},
+		{"*", "1.3.0"},
+		{"x", "1.3.0"},
+
+		{"~1.2.3", "1.2.9"},
+		{"~1.2", "1.2.9"},
+		{"~1", "1.3.0"},
+		{"~0.1.0", "0.1.5"},
+		{"~0.0.3", "0.0.4"},
+		{"~1.5", ""},
+
+		{"^1.2.3", "1.3.0"},
+		{"^1.2", "1.3.0"},
+		{"^1", "1.3.0"},
+		{"^0.1.0", "0.1.5"},
+		{"^0.1", "0.1.5"},
+		{"^0.0.3", "0.0.3"},
+		{"^0.0.2", ""},
+		{"^0.0", "0.0.4"},
+		{"^0.0.x", "0.0.4"},
+		{"^0", "0.2.0"},
+		{"^0.x", "0.2.0"},
+		{"^1.x", "1.3.0"},
+
+		{"1.x", "1.3.0"},
+		{"1.2.x", "1.2.9"},
+		{"1.2", "1.2.9"},
+		{"0", "0.2.0"},
+		{"1.2.3", "1.2.3"},
+		{"=1.2.0", "1.2.0"},
+		{"1.2.4", ""},
+
+		{">=1.2.0 <1.2.9", "1.2.3"},
+		{">0.1.0 <=1.0.0", "1.0.0"},
+		{"<0.1.0", "0.0.4"},
+		{">1.3.0", ""},
+
+		{"~1.4", ""},
+		{"^1.4.0-beta.1", "1.4.0-beta.2"},
+		{">=1.4.0-beta.1 <1.4.0-beta.2", "1.4.0-beta.1"},
+		{"1.4.0-beta.1", "1.4.0-beta.1"},
+		{"^2.0.0-rc.1", "2.0.0-rc.1"},
+		{">=1.3.0", "1.3.0"},
+	}
+
+	for _, test := range tests {
+		selected, err := selectPluginVersion(test.constraint, registryVersions)
+		if err != nil {
+			t.Errorf("Unexpected error for constraint %q: %s", test.constraint, err)
+			continue
+		}
+		if selected != test.expected {
+			t.Errorf("Expected constraint %q to select %q, got %q", test.constraint, test.expected, selected)
+		}
+	}
+}
+
+func TestSelectPluginVersionWithInvalidConstraint(t *testing.T) {
+	for _, constraint := range []string{"^a.b", "~1.2.3.4", ">=1.x", "<", "1..2"} {
+		if _, err := selectPluginVersion(constraint, registryVersions); err == nil {
+			t.Errorf("Expected constraint %q to be invalid", constraint)
+		}
+	}
+}
+
+func TestIsPluginVersionConstraint(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint bool
+	}{
+		{"latest", true},
+		{"^0.46", true},
+		{"~1.2.0", true},
+		{"1.x", true},
+		{"1.2.X", true},
+		{">=1.0 <2.0", true},
+		{"1.2.0", false},
+		{"1.4.0-beta.1", false},
+		{"next", false},
+	}
+
+	for _, test := range tests {
+		if constraint := isPluginVersionConstraint(test.version); constraint != test.constraint {
+			t.Errorf("Expected isPluginVersionConstraint(%q) to be %t", test.version, test.constraint)
+		}
+	}
+}
@@ -1,8 +1,8 @@
 package workspace
 
 import (
-	"encoding/json"
 	"errors"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,125 +16,47 @@ import (
 	"github.com/eclipse/che-plugin-broker/model"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
-// TODO : change this because we don't expect plugin metas anymore, but plugin FQNs in the config maps
-func setupPluginInitContainers(names workspaceProperties, podSpec *corev1.PodSpec, pluginFQNs []model.PluginFQN) ([]runtime.Object, error) {
-	var k8sObjects []runtime.Object
-
-	type initContainerDef struct {
-		imageName  string
-		pluginFQNs []model.PluginFQN
-	}
-
-	for _, def := range []initContainerDef{
-		initContainerDef{
-			imageName:  "che.workspace.plugin_broker.init.image",
-			pluginFQNs: []model.PluginFQN{},
-		},
-		initContainerDef{
-			imageName:  "che.workspace.plugin_broker.unified.image",
-			pluginFQNs: pluginFQNs,
-		},
-	} {
-		brokerImage := controllerConfig.getProperty(def.imageName)
-		if brokerImage == nil {
-			return nil, errors.New("Unknown broker docker image for : " + def.imageName)
-		}
-
-		volumeMounts := []corev1.VolumeMount{
-			corev1.VolumeMount{
-				MountPath: "/plugins/",
-				Name:      "claim-che-workspace",
-				SubPath:   names.workspaceId + "/plugins/",
-			},
-		}
-
-		containerName := strings.ReplaceAll(
-			strings.TrimSuffix(
-				strings.TrimPrefix(def.imageName, "che.workspace.plugin_"),
-				".image"),
-			".", "-")
-		args := []string{
-			"-disable-push",
-			"-runtime-id",
-			join(":",
-				names.workspaceId,
-				"default",
-				"anonymous",
-			),
-			"--registry-address",
-			controllerConfig.getPluginRegistry(),
-		}
-
-		if len(def.pluginFQNs) > 0 {
-
-			// TODO: Voir comment le unified broker est défini dans le yaml
-			// et le définir de la même manière ici.
-			// Voir aussi comment ça se fait qu'on ne met pas de volume =>
-			//    => log du côté operator pour voir ce qu'il y a dans les PluginFQNs
-
-			configMapName := containerName + "-broker-config-map"
-			configMapVolume := containerName + "-broker-config-volume"
-			configMapContent, err := json.MarshalIndent(pluginFQNs, "", "")
-			if err != nil {
-				return nil, err
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// pluginExtensionDownloads returns the extension archives to download for a Theia or VS Code plugin.
+// Extensions of plugins that run in a sidecar go into the directory the sidecar reads its plugins from.
+// Extensions of local and private registries are downloaded from the extensions server of the controller.
+// The checksum of an extension can be published in the meta.yaml as a `#sha256={checksum}` fragment of its URL.
+func pluginExtensionDownloads(pluginMeta *model.PluginMeta, pluginContainers []model.Container) ([]ExtensionDownload, error) {
+	destinationDir := "/plugins"
+	for _, container := range pluginContainers {
+		for _, env := range container.Env {
+			if env.Name == "THEIA_PLUGINS" && strings.HasPrefix(env.Value, "local-dir://") {
+				destinationDir = strings.TrimPrefix(env.Value, "local-dir://")
 			}
+		}
+	}
 
-			configMap := corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      configMapName,
-					Namespace: names.namespace,
-					Labels: map[string]string{
-						"che.workspace_id": names.workspaceId,
-					},
-				},
-				Data: map[string]string{
-					"config.json": string(configMapContent),
-				},
+	filePrefix := strings.ReplaceAll(pluginMeta.Publisher+"_"+pluginMeta.Name+"_"+pluginMeta.Version, "/", "_")
+	downloads := []ExtensionDownload{}
+	for index, extension := range pluginMeta.Spec.Extensions {
+		checksum := ""
+		if urlAndFragment := strings.SplitN(extension, "#", 2); len(urlAndFragment) == 2 && strings.HasPrefix(urlAndFragment[1], "sha256=") {
+			checksum = strings.ToLower(strings.TrimPrefix(urlAndFragment[1], "sha256="))
+			if !sha256Pattern.MatchString(checksum) {
+				return nil, errors.New("Invalid checksum of extension '" + extension + "' of plugin '" + pluginMeta.ID + "'")
 			}
-			k8sObjects = append(k8sObjects, &configMap)
-
-			volumeMounts = append(volumeMounts, corev1.VolumeMount{
-				MountPath: "/broker-config/",
-				Name:      configMapVolume,
-				ReadOnly:  true,
-			})
-
-			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
-				Name: configMapVolume,
-				VolumeSource: corev1.VolumeSource{
-					ConfigMap: &corev1.ConfigMapVolumeSource{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: configMapName,
-						},
-					},
-				},
-			})
-
-			args = append(args,
-				"-metas",
-				"/broker-config/config.json",
-			)
 		}
-
-		podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
-			Name:  containerName,
-			Image: *brokerImage,
-			Args:  args,
-
-			ImagePullPolicy:          corev1.PullIfNotPresent,
-			VolumeMounts:             volumeMounts,
-			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		downloads = append(downloads, ExtensionDownload{
+			URL:            extensionDownloadUrl(extension),
+			DestinationDir: destinationDir,
+			FileName:       join(".", filePrefix, strconv.Itoa(index), extensionFileName(extension)),
+			Checksum:       checksum,
 		})
 	}
-	return k8sObjects, nil
+	return downloads, nil
 }
 
-func setupChePlugin(names workspaceProperties, component *workspaceApi.ComponentSpec) (*ComponentInstanceStatus, error) {
-	theIoUtil := utils.New()
+func setupChePlugin(names workspaceProperties, component *workspaceApi.ComponentSpec, resolvedPluginIds map[string]string) (*ComponentInstanceStatus, error) {
+	theIoUtil := NewCachingIoUtil()
 	theRand := commonBroker.NewRand()
 
 	pluginFQN := model.PluginFQN{}
@@ -143,26 +65,27 @@ func setupChePlugin(names workspaceProperties, component *workspaceApi.Component
 	if idPartsLen < 3 {
 		return nil, errors.New("Invalid component ID: " + *component.Id)
 	}
-	pluginFQN.ID = strings.Join(idParts[idPartsLen-3:3], "/")
+	pluginFQN.ID = strings.Join(idParts[idPartsLen-3:], "/")
 	if idPartsLen > 3 {
 		pluginFQN.Registry = strings.Join(idParts[0:idPartsLen-3], "/")
+	} else if component.RegistryUrl != nil {
+		pluginFQN.Registry = strings.TrimSuffix(*component.RegistryUrl, "/")
 	}
 
-	pluginMeta, err := utils.GetPluginMeta(pluginFQN, controllerConfig.getPluginRegistry(), theIoUtil)
-	if err != nil {
-		return nil, err
+	if resolvedPluginId, alreadyResolved := resolvedPluginIds[*component.Id]; alreadyResolved {
+		pluginFQN.ID = resolvedPluginId
+	} else if isPluginVersionConstraint(idParts[idPartsLen-1]) {
+		var err error
+		pluginFQN, err = resolvePluginVersion(pluginFQN, theIoUtil)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	pluginMetas := []model.PluginMeta{*pluginMeta}
-	err = utils.ResolveRelativeExtensionPaths(pluginMetas, controllerConfig.getPluginRegistry())
-	if err != nil {
-		return nil, err
-	}
-	err = utils.ValidateMetas(pluginMetas...)
+	pluginMeta, _, err := getPluginMeta(pluginFQN, theIoUtil)
 	if err != nil {
 		return nil, err
 	}
-	pluginMeta = &pluginMetas[0]
 
 	chePlugin := metadataBroker.ConvertMetaToPlugin(*pluginMeta)
 
@@ -176,7 +99,14 @@ func setupChePlugin(names workspaceProperties, component *workspaceApi.Component
 		Machines:                   map[string]MachineDescription{},
 		Endpoints:                  []workspaceApi.Endpoint{},
 		ContributedRuntimeCommands: []CheWorkspaceCommand{},
-		PluginFQN:                  &pluginFQN,
+		PluginId:                   *component.Id,
+		ResolvedPluginId:           pluginFQN.ID,
+	}
+	if isTheiaOrVsCodePlugin {
+		componentInstanceStatus.Extensions, err = pluginExtensionDownloads(pluginMeta, chePlugin.Containers)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if len(chePlugin.Containers) == 0 {
 		return componentInstanceStatus, nil
@@ -213,6 +143,7 @@ func setupChePlugin(names workspaceProperties, component *workspaceApi.Component
 				Value: envVarDef.Value,
 			})
 		}
+		envVars = append(envVars, componentEnvVars(component)...)
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "CHE_MACHINE_NAME",
 			Value: machineName,
@@ -232,6 +163,7 @@ func setupChePlugin(names workspaceProperties, component *workspaceApi.Component
 			},
 			VolumeMounts:             volumeMounts,
 			Env:                      append(envVars, commonEnvironmentVariables(names)...),
+			EnvFrom:                  componentEnvFrom(component),
 			TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 		}
 		podTemplate.Spec.Containers = append(podTemplate.Spec.Containers, container)
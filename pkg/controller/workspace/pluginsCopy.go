@@ -0,0 +1,133 @@
+package workspace
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Directory, relative to the root of the workspace persistent volume, that contains
+// the extension archives shared by all the workspaces of the namespace
+const pluginsCacheDir = "plugins-cache"
+
+// Shell functions used by the plugins copy init container when the extension cache is enabled.
+//
+// Cache entries are keyed by the SHA-256 of the extension URL, and stored along with the checksum
+// of their content, which is verified before each use, as well as against the checksum published
+// in the plugin meta.yaml, if any.
+// The modification time of an entry is updated each time it is used, so that the least recently used
+// entries can be evicted when the cache grows over its maximum size.
+//
+// Since the cache is shared by the workspaces of the namespace, its entries are only updated
+// under locks: each entry has its own exclusive lock, and the whole cache a lock that is shared
+// while fetching entries, and exclusive while evicting them.
+const pluginsCacheFunctions = `
+fetch() {
+  key=$(printf '%s' "$1" | sha256sum | cut -d' ' -f1)
+  (
+    flock -s 8
+    flock -x 9
+    if [ -f "$cache/$key" ] && (cd "$cache" && sha256sum -c --status "$key.sha256" 2>/dev/null) &&
+      { [ -z "$3" ] || grep -q "^$3 " "$cache/$key.sha256"; }; then
+      touch "$cache/$key"
+    else
+      rm -f "$cache/$key" "$cache/$key.sha256"
+      tmp=$(mktemp "$cache/.download.XXXXXX")
+      curl -sSfL -o "$tmp" "$1"
+      [ -z "$3" ] || verify "$tmp" "$3" || { rm -f "$tmp"; exit 1; }
+      mv -f "$tmp" "$cache/$key"
+      (cd "$cache" && sha256sum "$key" > "$key.sha256")
+    fi
+    ln -f "$cache/$key" "$2" 2>/dev/null || cp -f "$cache/$key" "$2"
+  ) 8>"$cache/.lock" 9>"$cache/$key.lock"
+}
+evict() {
+  (
+    flock -x 8
+    cd "$cache"
+    rm -f .download.*
+    for lock in *.lock; do
+      [ -e "${lock%.lock}" ] || rm -f "$lock"
+    done
+    while [ "$(du -sb . | cut -f1)" -gt "$1" ]; do
+      oldest=$(ls -tr | grep -v -e '\.sha256$' -e '\.lock$' | head -n 1)
+      [ -n "$oldest" ] || break
+      rm -f "$oldest" "$oldest.sha256" "$oldest.lock"
+    done
+  ) 8>"$cache/.lock"
+}`
+
+// Shell function that verifies the SHA-256 checksum of a downloaded extension
+const verifyChecksumFunction = `
+verify() {
+  printf '%s  %s\n' "$2" "$1" | sha256sum -c --quiet - || { echo "Unexpected checksum of $1" >&2; return 1; }
+}`
+
+// setupPluginsCopyInitContainer adds an init container that cleans the plugins volume
+// and downloads into it the extensions of the Theia and VS Code plugins of the workspace.
+// When the extension cache is enabled, the extensions are taken from the cache of the namespace
+// if already downloaded by another workspace.
+// The plugins copy image is expected to provide curl, sha256sum and, for the extension cache, flock.
+func setupPluginsCopyInitContainer(names workspaceProperties, podSpec *corev1.PodSpec, extensions []ExtensionDownload) {
+	cacheMaxSize := controllerConfig.getPluginsCacheMaxSize()
+
+	var volumeMount corev1.VolumeMount
+	script := []string{"set -e", verifyChecksumFunction}
+	if cacheMaxSize > 0 {
+		// The whole persistent volume is mounted so that extensions can be hard-linked from the cache
+		volumeMount = corev1.VolumeMount{
+			MountPath: "/che-volume/",
+			Name:      "claim-che-workspace",
+		}
+		script = append(script,
+			"plugins="+shellQuote(path.Join("/che-volume", names.workspaceId, "plugins")),
+			"cache="+shellQuote(path.Join("/che-volume", pluginsCacheDir)),
+			`mkdir -p "$plugins" "$cache"`,
+			pluginsCacheFunctions,
+		)
+	} else {
+		volumeMount = corev1.VolumeMount{
+			MountPath: "/plugins/",
+			Name:      "claim-che-workspace",
+			SubPath:   names.workspaceId + "/plugins/",
+		}
+		script = append(script, "plugins=/plugins")
+	}
+	script = append(script, `rm -rf "$plugins"/*`)
+
+	for _, extension := range extensions {
+		destinationDir := `"$plugins"` + shellQuote(strings.TrimPrefix(extension.DestinationDir, "/plugins"))
+		destination := `"$plugins"` + shellQuote(strings.TrimPrefix(path.Join(extension.DestinationDir, extension.FileName), "/plugins"))
+		script = append(script, "mkdir -p "+destinationDir)
+		if cacheMaxSize > 0 {
+			script = append(script, join(" ", "fetch", shellQuote(extension.URL), destination, shellQuote(extension.Checksum)))
+		} else {
+			script = append(script, join(" ", "curl -sSfL -o", destination, shellQuote(extension.URL)))
+			if extension.Checksum != "" {
+				script = append(script, join(" ", "verify", destination, shellQuote(extension.Checksum)))
+			}
+		}
+	}
+	if cacheMaxSize > 0 {
+		script = append(script, "evict "+strconv.FormatInt(cacheMaxSize, 10))
+	}
+
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:    "plugins-copy",
+		Image:   controllerConfig.getPluginsCopyImage(),
+		Command: []string{"/bin/sh"},
+		Args: []string{
+			"-c",
+			strings.Join(script, "\n"),
+		},
+		ImagePullPolicy:          corev1.PullIfNotPresent,
+		VolumeMounts:             []corev1.VolumeMount{volumeMount},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	})
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "'\\''") + "'"
+}
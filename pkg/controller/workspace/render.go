@@ -0,0 +1,196 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"github.com/che-incubator/che-workspace-crd-operator/pkg/controller/objectsync"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	routeV1 "github.com/openshift/api/route/v1"
+	templateV1 "github.com/openshift/api/template/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+)
+
+// Key of the rendered ConfigMap that contains the rendered objects
+const renderedObjectsKey = "objects.yaml"
+
+// SetupRenderConfig sets the controller config used to render workspaces outside of the controller,
+// since there is no controller ConfigMap to watch in this case.
+func SetupRenderConfig(configMap *corev1.ConfigMap, isOpenshift bool) {
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	controllerConfig.update(configMap)
+	controllerConfig.controllerIsOpenshift = isOpenshift
+}
+
+// RenderWorkspace returns all the K8s objects that the controller would create for the workspace,
+// in the order they are applied: the prerequisites, the component objects, the workspace deployment
+// and the WorkspaceExposure.
+// A workspace that has no UID, such as a workspace read from a file, gets a stable UID derived
+// from its namespace and name, so that rendering it twice gives the same objects.
+// Since there is no cluster to read from, the devfile source of the workspace cannot be a ConfigMap.
+func RenderWorkspace(workspace *workspaceApi.Workspace) ([]runtime.Object, error) {
+	_, objects, err := renderWorkspace(nil, workspace)
+	return objects, err
+}
+
+func renderWorkspace(clt client.Client, workspace *workspaceApi.Workspace) (*workspaceProperties, []runtime.Object, error) {
+	workspace = workspace.DeepCopy()
+	if workspace.UID == "" {
+		workspace.UID = types.UID(uuid.NewSHA1(uuid.NameSpaceOID, []byte(workspace.Namespace+"/"+workspace.Name)).String())
+	}
+
+	if err := resolveWorkspaceDevfile(clt, workspace); err != nil {
+		return nil, nil, err
+	}
+
+	prerequisites, err := managePrerequisites(workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workspaceProperties, workspaceExposure, componentInstanceStatuses, k8sObjects, err := convertToCoreObjects(workspace)
+	if err != nil {
+		return workspaceProperties, nil, err
+	}
+	workspaceProperties.rendering = true
+
+	k8sObjects, err = provisionAutomountObjects(clt, workspace, *workspaceProperties, componentInstanceStatuses, k8sObjects)
+	if err != nil {
+		return workspaceProperties, nil, err
+	}
+
+	ownerReference := metav1.NewControllerRef(workspace, workspaceApi.SchemeGroupVersion.WithKind("Workspace"))
+	objects := append([]runtime.Object{}, prerequisites...)
+	for _, k8sObject := range append(k8sObjects, workspaceExposure) {
+		k8sObjectAsMetaObject, isMeta := k8sObject.(metav1.Object)
+		if !isMeta {
+			return workspaceProperties, nil, errors.New("Converted objects are not valid K8s objects")
+		}
+		k8sObjectAsMetaObject.SetOwnerReferences([]metav1.OwnerReference{*ownerReference})
+		k8sObjectAsMetaObject.SetLabels(withWorkspaceIdLabel(k8sObjectAsMetaObject.GetLabels(), *workspaceProperties))
+		objects = append(objects, k8sObject)
+	}
+	return workspaceProperties, objects, nil
+}
+
+// WriteObjectsYaml writes the objects as a multi-document YAML stream, with their `apiVersion` and `kind` set
+func WriteObjectsYaml(objects []runtime.Object, out io.Writer) error {
+	theScheme := runtime.NewScheme()
+	scheme.AddToScheme(theScheme)
+	templateV1.AddToScheme(theScheme)
+	routeV1.AddToScheme(theScheme)
+	workspaceApi.SchemeBuilder.AddToScheme(theScheme)
+
+	for _, obj := range objects {
+		gvk, err := apiutil.GVKForObject(obj, theScheme)
+		if err != nil {
+			return err
+		}
+		obj = obj.DeepCopyObject()
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(out, "---\n"); err != nil {
+			return err
+		}
+		if _, err = out.Write(content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderedConfigMapName(workspace *workspaceApi.Workspace) string {
+	return workspace.Name + "-rendered"
+}
+
+// isRenderedConfigMap returns whether the object is the ConfigMap that the objects of the workspace are rendered into,
+// which is kept along with the workspace objects as long as the workspace is annotated with `org.eclipse.che.workspace/render`
+func isRenderedConfigMap(workspace *workspaceApi.Workspace, gvk schema.GroupVersionKind, name string) bool {
+	return workspace.Annotations[RENDER_ANNOTATION] == "true" && gvk.GroupKind() == corev1.SchemeGroupVersion.WithKind("ConfigMap").GroupKind() &&
+		name == renderedConfigMapName(workspace)
+}
+
+// renderWorkspaceObjects stores the objects that would be created for a stopped workspace annotated with
+// `org.eclipse.che.workspace/render` in the `{workspace}-rendered` ConfigMap, instead of creating them.
+func (r *ReconcileWorkspace) renderWorkspaceObjects(workspace *workspaceApi.Workspace, reqLogger logr.Logger) (reconcile.Result, error) {
+	message, err := r.applyRenderedObjects(workspace, reqLogger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, r.updateRenderedStatus(workspace, message)
+}
+
+// applyRenderedObjects stores the objects rendered for the workspace in the `{workspace}-rendered` ConfigMap,
+// and returns the message that describes the outcome. Started workspaces are rendered along with their reconcile.
+func (r *ReconcileWorkspace) applyRenderedObjects(workspace *workspaceApi.Workspace, reqLogger logr.Logger) (string, error) {
+	reqLogger.Info("Rendering K8s Objects")
+	workspaceProperties, objects, err := renderWorkspace(r.syncer.Client(), workspace)
+	rendered := &strings.Builder{}
+	if err == nil {
+		err = WriteObjectsYaml(objects, rendered)
+	}
+	if err != nil {
+		reqLogger.Error(err, "Error when rendering K8S objects")
+		return "Cannot render the workspace objects: " + err.Error(), nil
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      renderedConfigMapName(workspace),
+			Namespace: workspace.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(workspace, workspaceApi.SchemeGroupVersion.WithKind("Workspace")),
+			},
+		},
+		Data: map[string]string{
+			renderedObjectsKey: rendered.String(),
+		},
+	}
+	configMap.SetLabels(withWorkspaceIdLabel(nil, *workspaceProperties))
+
+	outcome, err := r.syncer.Apply(configMap)
+	if err != nil {
+		reqLogger.Error(err, "Error when applying the rendered ConfigMap", "name", configMap.Name)
+		return "", err
+	}
+	if outcome != objectsync.Unchanged {
+		reqLogger.Info("  => Rendered K8s objects into ConfigMap", "name", configMap.Name)
+	}
+	return renderedMessagePrefix + "'" + configMap.Name + "'", nil
+}
+
+// Prefix of the status message of the rendered workspaces
+const renderedMessagePrefix = "Rendered the workspace objects into ConfigMap "
+
+// clearRenderedMessage removes the status message of the rendering, when the workspace is reconciled normally
+func clearRenderedMessage(workspace *workspaceApi.Workspace) {
+	if strings.HasPrefix(workspace.Status.Message, renderedMessagePrefix) {
+		workspace.Status.Message = ""
+	}
+}
+
+func (r *ReconcileWorkspace) updateRenderedStatus(workspace *workspaceApi.Workspace, message string) error {
+	if workspace.Status.Message == message {
+		return nil
+	}
+	workspace.Status.Message = message
+	return r.Status().Update(context.TODO(), workspace)
+}
@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Pod annotation that sets the seccomp profile of all the containers of a pod
+const seccompPodAnnotation = "seccomp.security.alpha.kubernetes.io/pod"
+
+// securityProfile is the security configuration of the workspace pods, read from the controller configuration
+type securityProfile struct {
+	// User the containers run as, or nil to keep the user of the images
+	runAsUser *int64
+	// Group that owns the volumes of the pod, and that all the containers belong to
+	fsGroup *int64
+	// Whether the containers are required to run as a non-root user
+	runAsNonRoot bool
+	// Capabilities dropped from all the containers
+	dropCapabilities []corev1.Capability
+	// Whether the root filesystem of the plugin and editor sidecars, and of the che-rest-apis container, is read-only
+	readOnlySidecars bool
+	// Seccomp profile of the pod, such as `runtime/default`, or empty to keep the default of the container runtime
+	seccompProfile string
+}
+
+// applySecurityProfiles applies the security profile to the pods of the workspace: the main workspace pod,
+// and the pods of the kubernetes and openshift components. Only the containers of the main workspace pod are sidecars.
+func applySecurityProfiles(profile securityProfile, k8sObjects []runtime.Object, mainPodTemplate *corev1.PodTemplateSpec, sidecars map[string]bool) {
+	for _, obj := range k8sObjects {
+		podTemplate, _ := k8sLikePodTemplate(obj)
+		if podTemplate == nil {
+			continue
+		}
+		if podTemplate == mainPodTemplate {
+			applySecurityProfile(profile, podTemplate, sidecars)
+		} else {
+			applySecurityProfile(profile, podTemplate, map[string]bool{})
+		}
+	}
+}
+
+// applySecurityProfile sets the security context of the workspace pod, and of each of its containers and init containers.
+// Security settings already set on the pod or on a container are kept.
+func applySecurityProfile(profile securityProfile, podTemplate *corev1.PodTemplateSpec, sidecars map[string]bool) {
+	if podTemplate.Spec.SecurityContext == nil {
+		podTemplate.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	podSecurityContext := podTemplate.Spec.SecurityContext
+	if podSecurityContext.RunAsUser == nil {
+		podSecurityContext.RunAsUser = profile.runAsUser
+	}
+	if podSecurityContext.FSGroup == nil {
+		podSecurityContext.FSGroup = profile.fsGroup
+	}
+	if podSecurityContext.RunAsNonRoot == nil && profile.runAsNonRoot {
+		runAsNonRoot := true
+		podSecurityContext.RunAsNonRoot = &runAsNonRoot
+	}
+	if profile.seccompProfile != "" {
+		if podTemplate.Annotations == nil {
+			podTemplate.Annotations = map[string]string{}
+		}
+		if _, exists := podTemplate.Annotations[seccompPodAnnotation]; !exists {
+			podTemplate.Annotations[seccompPodAnnotation] = profile.seccompProfile
+		}
+	}
+
+	for i := range podTemplate.Spec.InitContainers {
+		applyContainerSecurityProfile(profile, &podTemplate.Spec.InitContainers[i], false)
+	}
+	for i := range podTemplate.Spec.Containers {
+		container := &podTemplate.Spec.Containers[i]
+		applyContainerSecurityProfile(profile, container, sidecars[container.Name])
+	}
+}
+
+func applyContainerSecurityProfile(profile securityProfile, container *corev1.Container, isSidecar bool) {
+	if container.SecurityContext == nil {
+		container.SecurityContext = &corev1.SecurityContext{}
+	}
+	securityContext := container.SecurityContext
+	if profile.runAsNonRoot && securityContext.AllowPrivilegeEscalation == nil {
+		allowPrivilegeEscalation := false
+		securityContext.AllowPrivilegeEscalation = &allowPrivilegeEscalation
+	}
+	if len(profile.dropCapabilities) > 0 {
+		if securityContext.Capabilities == nil {
+			securityContext.Capabilities = &corev1.Capabilities{}
+		}
+		if securityContext.Capabilities.Drop == nil {
+			securityContext.Capabilities.Drop = profile.dropCapabilities
+		}
+	}
+	if isSidecar && profile.readOnlySidecars && securityContext.ReadOnlyRootFilesystem == nil {
+		readOnlyRootFilesystem := true
+		securityContext.ReadOnlyRootFilesystem = &readOnlyRootFilesystem
+	}
+}
+
+// sidecarContainers returns the names of the containers of the plugin and editor components,
+// and of the che-rest-apis container
+func sidecarContainers(componentInstanceStatuses []ComponentInstanceStatus) map[string]bool {
+	sidecars := map[string]bool{
+		cheRestApisContainerName: true,
+	}
+	for _, componentInstanceStatus := range componentInstanceStatuses {
+		switch componentInstanceStatus.ComponentType {
+		case workspaceApi.CheEditor, workspaceApi.ChePlugin:
+			for machineName := range componentInstanceStatus.Machines {
+				sidecars[machineName] = true
+			}
+		}
+	}
+	return sidecars
+}
@@ -0,0 +1,156 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func renderSecurityProfile(t *testing.T, properties map[string]string) corev1.PodTemplateSpec {
+	SetupRenderConfig(&corev1.ConfigMap{Data: properties}, false)
+	profile := controllerConfig.getSecurityProfile()
+	podTemplate := corev1.PodTemplateSpec{}
+	precreateSubpathsInitContainer(workspaceProperties{workspaceId: "workspace1"}, &podTemplate.Spec, profile)
+	applySecurityProfile(profile, &podTemplate, map[string]bool{})
+	if len(podTemplate.Spec.InitContainers) != 1 {
+		t.Fatalf("Expected the precreate-subpaths init container, got %d init containers", len(podTemplate.Spec.InitContainers))
+	}
+	return podTemplate
+}
+
+func TestDefaultSecurityProfile(t *testing.T) {
+	podTemplate := renderSecurityProfile(t, map[string]string{})
+
+	podSecurityContext := podTemplate.Spec.SecurityContext
+	if podSecurityContext.RunAsUser != nil || podSecurityContext.FSGroup != nil || podSecurityContext.RunAsNonRoot != nil {
+		t.Errorf("Expected the default profile to keep the users of the images, got %+v", podSecurityContext)
+	}
+	initContainer := podTemplate.Spec.InitContainers[0]
+	if strings.Join(initContainer.Args, " ") != "-p -v -m 777 /tmp/che-workspaces/workspace1" {
+		t.Errorf("Expected the workspace directory to be writable by everyone without a filesystem group, got %v", initContainer.Args)
+	}
+	if initContainer.SecurityContext.Capabilities != nil || initContainer.SecurityContext.AllowPrivilegeEscalation != nil {
+		t.Errorf("Expected the default profile to keep the container security settings, got %+v", initContainer.SecurityContext)
+	}
+}
+
+func TestRestrictedSecurityProfile(t *testing.T) {
+	podTemplate := renderSecurityProfile(t, map[string]string{"security.profile": "restricted"})
+
+	podSecurityContext := podTemplate.Spec.SecurityContext
+	if podSecurityContext.RunAsUser == nil || *podSecurityContext.RunAsUser != 1724 {
+		t.Errorf("Expected the containers to run as user 1724, got %v", podSecurityContext.RunAsUser)
+	}
+	if podSecurityContext.FSGroup == nil || *podSecurityContext.FSGroup != 1724 {
+		t.Fatalf("Expected the filesystem group 1724, got %v", podSecurityContext.FSGroup)
+	}
+	if podSecurityContext.RunAsNonRoot == nil || !*podSecurityContext.RunAsNonRoot {
+		t.Error("Expected the containers to run as a non-root user")
+	}
+	initContainer := podTemplate.Spec.InitContainers[0]
+	script := strings.Join(initContainer.Args, " ")
+	if !strings.Contains(script, "chgrp 1724 /tmp/che-workspaces/workspace1") || !strings.Contains(script, "chmod 2770 /tmp/che-workspaces/workspace1") {
+		t.Errorf("Expected the workspace directory to belong to the filesystem group, got %q", script)
+	}
+	capabilities := initContainer.SecurityContext.Capabilities
+	if capabilities == nil || len(capabilities.Drop) != 1 || capabilities.Drop[0] != "ALL" {
+		t.Errorf("Expected all the capabilities to be dropped, got %+v", capabilities)
+	}
+}
+
+func TestRestrictedSecurityProfileWithoutFsGroup(t *testing.T) {
+	podTemplate := renderSecurityProfile(t, map[string]string{"security.profile": "restricted", "security.fs.group": ""})
+
+	if podTemplate.Spec.SecurityContext.FSGroup != nil {
+		t.Errorf("Expected an empty property to disable the filesystem group, got %v", *podTemplate.Spec.SecurityContext.FSGroup)
+	}
+	if args := strings.Join(podTemplate.Spec.InitContainers[0].Args, " "); !strings.Contains(args, "-m 777") {
+		t.Errorf("Expected the workspace directory to be writable by everyone without a filesystem group, got %q", args)
+	}
+}
+
+func TestSecurityProfileAppliesToComponentPods(t *testing.T) {
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{
+		"security.profile": "restricted",
+		"security.sidecars.read.only.root.filesystem": "true",
+	}}, false)
+	profile := controllerConfig.getSecurityProfile()
+
+	mainDeployment := &appsv1.Deployment{}
+	mainDeployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "theia"}}
+	componentDeployment := &appsv1.Deployment{}
+	componentDeployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "theia"}}
+	applySecurityProfiles(profile, []runtime.Object{componentDeployment, mainDeployment}, &mainDeployment.Spec.Template, map[string]bool{"theia": true})
+
+	for name, deployment := range map[string]*appsv1.Deployment{"main": mainDeployment, "component": componentDeployment} {
+		if deployment.Spec.Template.Spec.SecurityContext == nil || deployment.Spec.Template.Spec.SecurityContext.RunAsUser == nil {
+			t.Errorf("Expected the security profile to apply to the %s pod", name)
+		}
+	}
+	mainContainer := mainDeployment.Spec.Template.Spec.Containers[0]
+	if mainContainer.SecurityContext.ReadOnlyRootFilesystem == nil || !*mainContainer.SecurityContext.ReadOnlyRootFilesystem {
+		t.Error("Expected the sidecar of the main pod to have a read-only root filesystem")
+	}
+	if componentDeployment.Spec.Template.Spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem != nil {
+		t.Error("Expected the containers of component pods not to be sidecars")
+	}
+}
+
+func TestSSHKeysFileMode(t *testing.T) {
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{}}, false)
+	if mode := sshKeysFileMode(controllerConfig.getSecurityProfile()); mode != 0444 {
+		t.Errorf("Expected the SSH keys to be readable by all the users without a filesystem group, got %o", mode)
+	}
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{"security.profile": "restricted"}}, false)
+	if mode := sshKeysFileMode(controllerConfig.getSecurityProfile()); mode != 0440 {
+		t.Errorf("Expected the SSH keys to be readable by the filesystem group, got %o", mode)
+	}
+}
+
+func TestRestrictedSecurityProfileOnOpenShift(t *testing.T) {
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{"security.profile": "restricted"}}, true)
+	profile := controllerConfig.getSecurityProfile()
+	if profile.runAsUser != nil || profile.fsGroup != nil {
+		t.Errorf("Expected OpenShift to assign the user and the filesystem group, got %v and %v", profile.runAsUser, profile.fsGroup)
+	}
+	if !profile.runAsNonRoot {
+		t.Error("Expected the containers to run as a non-root user")
+	}
+}
+
+func TestSecurityProfileKeepsExistingSettings(t *testing.T) {
+	SetupRenderConfig(&corev1.ConfigMap{Data: map[string]string{
+		"security.profile":         "restricted",
+		"security.seccomp.profile": "runtime/default",
+	}}, false)
+	profile := controllerConfig.getSecurityProfile()
+
+	user := int64(1000)
+	allowPrivilegeEscalation := true
+	podTemplate := corev1.PodTemplateSpec{}
+	podTemplate.Annotations = map[string]string{seccompPodAnnotation: "unconfined"}
+	podTemplate.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsUser: &user}
+	podTemplate.Spec.Containers = []corev1.Container{
+		{Name: "privileged", SecurityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: &allowPrivilegeEscalation}},
+	}
+	applySecurityProfile(profile, &podTemplate, map[string]bool{})
+
+	if *podTemplate.Spec.SecurityContext.RunAsUser != 1000 {
+		t.Errorf("Expected the user of the pod to be kept, got %d", *podTemplate.Spec.SecurityContext.RunAsUser)
+	}
+	if podTemplate.Annotations[seccompPodAnnotation] != "unconfined" {
+		t.Errorf("Expected the seccomp profile of the pod to be kept, got '%s'", podTemplate.Annotations[seccompPodAnnotation])
+	}
+	if !*podTemplate.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation {
+		t.Error("Expected the privilege escalation setting of the container to be kept")
+	}
+
+	podTemplate = corev1.PodTemplateSpec{}
+	applySecurityProfile(profile, &podTemplate, map[string]bool{})
+	if podTemplate.Annotations[seccompPodAnnotation] != "runtime/default" {
+		t.Errorf("Expected the seccomp profile of the security profile, got '%s'", podTemplate.Annotations[seccompPodAnnotation])
+	}
+}
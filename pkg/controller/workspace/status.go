@@ -218,6 +218,8 @@ func (r *ReconcileWorkspace) updateStatusAfterWorkspaceChange(rs *reconcileStatu
 			rs.workspace.Status.AdditionalInfo["org.eclipse.che.workspace/componentstatuses"] = string(statusesAnnotation)
 		}
 
+		updateUptimeAccounting(&rs.workspace.Status, existingPhase)
+
 		rs.ReqLogger.V(1).Info("Status Update After Workspace Change : ", "status", rs.workspace.Status)
 		err := r.Status().Update(context.TODO(), rs.workspace)
 		if err != nil {
@@ -229,6 +231,15 @@ func (r *ReconcileWorkspace) updateStatusAfterWorkspaceChange(rs *reconcileStatu
 	}
 }
 
+// Status message of the workspaces without IDE URL. It doesn't replace the messages set by other parts of the reconcile.
+const noIdeMessage = "No endpoint of type 'ide' is exposed by the workspace components: add a 'cheEditor' component to the devfile to get an IDE URL"
+
+func clearNoIdeMessage(workspace *workspacev1alpha1.Workspace) {
+	if workspace.Status.Message == noIdeMessage {
+		workspace.Status.Message = ""
+	}
+}
+
 func (r *ReconcileWorkspace) updateFromWorkspaceExposure(exposure *workspacev1alpha1.WorkspaceExposure, workspace *workspacev1alpha1.Workspace) error {
 	if workspace.Status.AdditionalInfo == nil {
 		workspace.Status.AdditionalInfo = map[string]string {}
@@ -236,6 +247,7 @@ func (r *ReconcileWorkspace) updateFromWorkspaceExposure(exposure *workspacev1al
 	if exposure.Status.Phase != workspacev1alpha1.WorkspaceExposureReady {
 		delete(workspace.Status.AdditionalInfo, "org.eclipse.che.workspace/runtime")
 		workspace.Status.IdeUrl = ""
+		clearNoIdeMessage(workspace)
 	} else {
 
 		statusesAnnotation := workspace.Status.AdditionalInfo["org.eclipse.che.workspace/componentstatuses"]
@@ -251,6 +263,7 @@ func (r *ReconcileWorkspace) updateFromWorkspaceExposure(exposure *workspacev1al
 
 		commands := []CheWorkspaceCommand{}
 		machines := map[string]CheWorkspaceMachine{}
+		workspace.Status.IdeUrl = ""
 
 		for _, status := range statuses {
 			commands = append(commands, status.ContributedRuntimeCommands...)
@@ -288,6 +301,14 @@ func (r *ReconcileWorkspace) updateFromWorkspaceExposure(exposure *workspacev1al
 			}
 		}
 
+		if workspace.Status.IdeUrl == "" && !isEditorFree(workspace) {
+			if workspace.Status.Message == "" {
+				workspace.Status.Message = noIdeMessage
+			}
+		} else {
+			clearNoIdeMessage(workspace)
+		}
+
 		defaultEnv := "default"
 		runtime := CheWorkspaceRuntime{
 			ActiveEnv: &defaultEnv,
@@ -382,6 +403,8 @@ func (r *ReconcileWorkspace) updateStatusFromOwnedObjects(workspace *workspacev1
 			)
 		}
 	}
+	updateUptimeAccounting(&workspace.Status, existingPhase)
+
 	log.V(1).Info("Status Update After Change To Owned Objects : ", "status", workspace.Status)
 	r.Status().Update(context.TODO(), workspace)
 
@@ -391,6 +414,38 @@ func (r *ReconcileWorkspace) updateStatusFromOwnedObjects(workspace *workspacev1
 	return reconcileResult, nil
 }
 
+// updateUptimeAccounting records the start and stop times and the cumulative running time
+// when the workspace enters or leaves the Running phase.
+// The `runningSince` time, shown as the uptime of the workspace, is only set while the workspace is running.
+// Only the starts from the Stopped or Failed phases are counted, not the rollouts of a running workspace.
+func updateUptimeAccounting(status *workspacev1alpha1.WorkspaceStatus, previousPhase workspacev1alpha1.WorkspacePhase) {
+	if previousPhase == status.Phase {
+		return
+	}
+	switch previousPhase {
+	case "", workspacev1alpha1.WorkspacePhaseStopped, workspacev1alpha1.WorkspacePhaseFailed:
+		if status.Phase == workspacev1alpha1.WorkspacePhaseStarting || status.Phase == workspacev1alpha1.WorkspacePhaseRunning {
+			status.StartCount++
+		}
+	}
+	now := metav1.Now()
+	if status.Phase == workspacev1alpha1.WorkspacePhaseRunning {
+		status.LastStartTime = &now
+		status.RunningSince = &now
+	} else if previousPhase == workspacev1alpha1.WorkspacePhaseRunning {
+		status.LastStopTime = &now
+		runningSince := status.RunningSince
+		if runningSince == nil {
+			// Workspaces started before `runningSince` existed
+			runningSince = status.LastStartTime
+		}
+		if runningSince != nil {
+			status.RunningSeconds += int64(now.Sub(runningSince.Time).Seconds())
+		}
+		status.RunningSince = nil
+	}
+}
+
 var podConditionTypeToWorkspaceConditionType = map[corev1.PodConditionType]workspacev1alpha1.WorkspaceConditionType{
 	corev1.PodScheduled:   workspacev1alpha1.WorkspaceConditionScheduled,
 	corev1.PodInitialized: workspacev1alpha1.WorkspaceConditionInitialized,
@@ -0,0 +1,155 @@
+package workspace
+
+import (
+	"testing"
+	"time"
+
+	workspaceApi "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUptimeAccountingOnStart(t *testing.T) {
+	status := workspaceApi.WorkspaceStatus{
+		Phase:          workspaceApi.WorkspacePhaseRunning,
+		RunningSeconds: 60,
+		StartCount:     1,
+	}
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseStarting)
+
+	if status.StartCount != 1 {
+		t.Errorf("Expected the start count to be incremented when the workspace starts, not when it runs, got %d", status.StartCount)
+	}
+	if status.LastStartTime == nil || status.RunningSince == nil {
+		t.Fatal("Expected the start time and the running since time to be set")
+	}
+	if !status.LastStartTime.Equal(status.RunningSince) {
+		t.Errorf("Expected the running since time %v to be the start time %v", status.RunningSince, status.LastStartTime)
+	}
+	if status.RunningSeconds != 60 {
+		t.Errorf("Expected the running time to be unchanged until the workspace stops, got %d", status.RunningSeconds)
+	}
+}
+
+func TestUptimeAccountingCountsStarts(t *testing.T) {
+	status := workspaceApi.WorkspaceStatus{Phase: workspaceApi.WorkspacePhaseStarting}
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseStopped)
+	if status.StartCount != 1 {
+		t.Errorf("Expected the start from the Stopped phase to be counted, got %d", status.StartCount)
+	}
+
+	status.Phase = workspaceApi.WorkspacePhaseRunning
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseStarting)
+	// A rollout of the running workspace
+	status.Phase = workspaceApi.WorkspacePhaseStarting
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseRunning)
+	status.Phase = workspaceApi.WorkspacePhaseRunning
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseStarting)
+	if status.StartCount != 1 {
+		t.Errorf("Expected the rollout of a running workspace not to be counted as a start, got %d", status.StartCount)
+	}
+
+	status.Phase = workspaceApi.WorkspacePhaseStarting
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseFailed)
+	if status.StartCount != 2 {
+		t.Errorf("Expected the start from the Failed phase to be counted, got %d", status.StartCount)
+	}
+}
+
+func TestUptimeAccountingOnStop(t *testing.T) {
+	startTime := metav1.NewTime(time.Now().Add(-90 * time.Second))
+	status := workspaceApi.WorkspaceStatus{
+		Phase:          workspaceApi.WorkspacePhaseStopping,
+		LastStartTime:  &startTime,
+		RunningSince:   &startTime,
+		RunningSeconds: 60,
+		StartCount:     2,
+	}
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseRunning)
+
+	if status.RunningSince != nil {
+		t.Errorf("Expected the running since time to be cleared, got %v", status.RunningSince)
+	}
+	if status.LastStopTime == nil {
+		t.Fatal("Expected the stop time to be set")
+	}
+	if status.RunningSeconds < 150 || status.RunningSeconds > 155 {
+		t.Errorf("Expected the run of about 90 seconds to be added to the running time, got %d", status.RunningSeconds)
+	}
+	if status.LastStartTime == nil || !status.LastStartTime.Equal(&startTime) {
+		t.Errorf("Expected the last start time to be kept, got %v", status.LastStartTime)
+	}
+	if status.StartCount != 2 {
+		t.Errorf("Expected the start count to be unchanged, got %d", status.StartCount)
+	}
+}
+
+func TestUptimeAccountingOnStopOfWorkspaceStartedWithoutRunningSince(t *testing.T) {
+	startTime := metav1.NewTime(time.Now().Add(-30 * time.Second))
+	status := workspaceApi.WorkspaceStatus{
+		Phase:         workspaceApi.WorkspacePhaseFailed,
+		LastStartTime: &startTime,
+	}
+	updateUptimeAccounting(&status, workspaceApi.WorkspacePhaseRunning)
+
+	if status.RunningSeconds < 30 || status.RunningSeconds > 35 {
+		t.Errorf("Expected the running time to be computed from the last start time, got %d", status.RunningSeconds)
+	}
+}
+
+func TestUptimeAccountingWithoutRunningTransition(t *testing.T) {
+	for _, transition := range []struct {
+		previous workspaceApi.WorkspacePhase
+		current  workspaceApi.WorkspacePhase
+	}{
+		{workspaceApi.WorkspacePhaseRunning, workspaceApi.WorkspacePhaseRunning},
+		{workspaceApi.WorkspacePhaseStopped, workspaceApi.WorkspacePhaseStarting},
+		{workspaceApi.WorkspacePhaseStarting, workspaceApi.WorkspacePhaseFailed},
+		{workspaceApi.WorkspacePhaseStopping, workspaceApi.WorkspacePhaseStopped},
+	} {
+		startTime := metav1.NewTime(time.Now().Add(-time.Hour))
+		status := workspaceApi.WorkspaceStatus{
+			Phase:          transition.current,
+			LastStartTime:  &startTime,
+			RunningSince:   &startTime,
+			RunningSeconds: 10,
+			StartCount:     1,
+		}
+		updateUptimeAccounting(&status, transition.previous)
+
+		if status.RunningSeconds != 10 || status.StartCount != 1 || status.LastStopTime != nil ||
+			status.RunningSince == nil || !status.RunningSince.Equal(&startTime) {
+			t.Errorf("Expected no change of the uptime accounting from %s to %s, got %+v", transition.previous, transition.current, status)
+		}
+	}
+}
+
+func TestNoIdeMessage(t *testing.T) {
+	exposure := &workspaceApi.WorkspaceExposure{}
+	exposure.Status.Phase = workspaceApi.WorkspaceExposureReady
+	newWorkspace := func(message string) *workspaceApi.Workspace {
+		workspace := &workspaceApi.Workspace{}
+		workspace.Status.Message = message
+		workspace.Status.AdditionalInfo = map[string]string{"org.eclipse.che.workspace/componentstatuses": "[]"}
+		return workspace
+	}
+	r := &ReconcileWorkspace{}
+
+	workspace := newWorkspace("")
+	r.updateFromWorkspaceExposure(exposure, workspace)
+	if workspace.Status.Message != noIdeMessage {
+		t.Errorf("Expected a message about the missing IDE, got %q", workspace.Status.Message)
+	}
+
+	workspace = newWorkspace("")
+	workspace.Annotations = map[string]string{SKIP_DEFAULT_EDITOR_ANNOTATION: "true"}
+	r.updateFromWorkspaceExposure(exposure, workspace)
+	if workspace.Status.Message != "" {
+		t.Errorf("Expected no message when the user opted out of the editor, got %q", workspace.Status.Message)
+	}
+
+	workspace = newWorkspace("Rendered the workspace objects into ConfigMap 'workspace1-rendered'")
+	r.updateFromWorkspaceExposure(exposure, workspace)
+	if workspace.Status.Message != "Rendered the workspace objects into ConfigMap 'workspace1-rendered'" {
+		t.Errorf("Expected the existing message to be kept, got %q", workspace.Status.Message)
+	}
+}
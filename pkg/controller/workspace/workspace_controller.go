@@ -6,25 +6,27 @@ import (
 	"context"
 	origLog "log"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 
 	workspacev1alpha1 "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"github.com/che-incubator/che-workspace-crd-operator/pkg/controller/objectsync"
 	brokerCfg "github.com/eclipse/che-plugin-broker/cfg"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -46,12 +48,20 @@ var configMapReference = client.ObjectKey{
 // Add creates a new Workspace Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	r, err := newReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, r)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) *ReconcileWorkspace {
-	return &ReconcileWorkspace{Client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager) (*ReconcileWorkspace, error) {
+	syncer, err := objectsync.New(mgr, "workspace-controller")
+	if err != nil {
+		return nil, err
+	}
+	return &ReconcileWorkspace{Client: mgr.GetClient(), syncer: syncer, scheme: mgr.GetScheme()}, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -86,6 +96,11 @@ func add(mgr manager.Manager, r *ReconcileWorkspace) error {
 		return err
 	}
 
+	err = watchAutomountObjects(c, mgr)
+	if err != nil {
+		return err
+	}
+
 	// Watch for changes to primary resource Workspace
 	err = c.Watch(&source.Kind{Type: &workspacev1alpha1.Workspace{}}, &handler.EnqueueRequestForObject{}, predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
@@ -106,7 +121,7 @@ func add(mgr manager.Manager, r *ReconcileWorkspace) error {
 				return false
 			}
 			if e.MetaNew.GetGeneration() == e.MetaOld.GetGeneration() {
-				return false
+				return reconciledAnnotationsChanged(e.MetaOld, e.MetaNew)
 			}
 			return true
 		},
@@ -132,6 +147,18 @@ func add(mgr manager.Manager, r *ReconcileWorkspace) error {
 	return nil
 }
 
+// Workspace annotations whose changes are reconciled, although they don't change the generation of the workspace
+var reconciledAnnotations = []string{REFRESH_DEVFILE_ANNOTATION, RENDER_ANNOTATION}
+
+func reconciledAnnotationsChanged(metaOld metav1.Object, metaNew metav1.Object) bool {
+	for _, annotation := range reconciledAnnotations {
+		if metaOld.GetAnnotations()[annotation] != metaNew.GetAnnotations()[annotation] {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *ReconcileWorkspace) Write(p []byte) (n int, err error) {
 	log.Info(string(p))
 	return len(p), nil
@@ -144,6 +171,8 @@ type ReconcileWorkspace struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client.Client
+	// Applies the workspace objects of any kind with server-side apply
+	syncer *objectsync.Syncer
 	scheme *runtime.Scheme
 }
 
@@ -195,11 +224,44 @@ func (r *ReconcileWorkspace) Reconcile(request reconcile.Request) (reconcile.Res
 		return r.updateStatusFromOwnedObjects(instance, reqLogger)
 	}
 
+	if instance.Annotations[RENDER_ANNOTATION] == "true" {
+		if !instance.Spec.Started {
+			return r.renderWorkspaceObjects(instance, reqLogger)
+		}
+		// A started workspace keeps being reconciled while it is rendered
+		if _, err := r.applyRenderedObjects(instance, reqLogger); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	clearRenderedMessage(instance)
+
 	var workspaceProperties *workspaceProperties
 	reconcileStatus.workspace = instance
 
 	defer r.updateStatusAfterWorkspaceChange(reconcileStatus)
 
+	err = resolveWorkspaceDevfile(r.syncer.Client(), instance)
+	if err != nil {
+		reqLogger.Error(err, "Error when resolving the devfile")
+		reconcileStatus.failure = err.Error()
+		return reconcile.Result{}, nil
+	}
+
+	instance.Status.MissingReferences = nil
+	if instance.Spec.Started {
+		missingReferences, err := missingEnvReferences(r.syncer.Client(), instance)
+		if err != nil {
+			reconcileStatus.failure = err.Error()
+			return reconcile.Result{}, err
+		}
+		if len(missingReferences) > 0 {
+			instance.Status.MissingReferences = missingReferences
+			reconcileStatus.failure = "The env of the workspace components references missing objects: " + strings.Join(missingReferences, ", ")
+			return reconcile.Result{RequeueAfter: missingReferencesRetryPeriod}, nil
+		}
+	}
+
 	prerequisites, err := managePrerequisites(instance)
 	if err != nil {
 		reconcileStatus.failure = err.Error()
@@ -230,16 +292,42 @@ func (r *ReconcileWorkspace) Reconcile(request reconcile.Request) (reconcile.Res
 			return reconcile.Result{}, err
 		} else {
 			if _, isPVC := found.(*corev1.PersistentVolumeClaim); !isPVC {
-				if _, isServiceAccount := found.(*corev1.ServiceAccount); !isServiceAccount {
-					err = r.Update(context.TODO(), prereq)
-					if err != nil {
-						log.Error(err, "")
+				// Keep the token and pull secrets that Kubernetes and OpenShift add to the ServiceAccount
+				if foundServiceAccount, isServiceAccount := found.(*corev1.ServiceAccount); isServiceAccount {
+					serviceAccount := prereq.(*corev1.ServiceAccount)
+					serviceAccount.Secrets = foundServiceAccount.Secrets
+					serviceAccount.ImagePullSecrets = mergeImagePullSecrets(serviceAccount.ImagePullSecrets, foundServiceAccount.ImagePullSecrets, addedImagePullSecrets(foundServiceAccount))
+					for name, value := range foundServiceAccount.Annotations {
+						if _, isSet := serviceAccount.Annotations[name]; !isSet {
+							serviceAccount.Annotations[name] = value
+						}
 					}
 				}
+				err = r.Update(context.TODO(), prereq)
+				if err != nil {
+					log.Error(err, "")
+				}
 			}
 		}
 	}
 
+	// Remove the permissions that the RBAC profile doesn't grant anymore
+	for _, roleBinding := range obsoleteRoleBindings(instance) {
+		found := &rbacv1.RoleBinding{}
+		err = r.Get(context.TODO(), types.NamespacedName{Name: roleBinding.Name, Namespace: roleBinding.Namespace}, found)
+		if err != nil && errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			reconcileStatus.failure = err.Error()
+			return reconcile.Result{}, err
+		}
+		reqLogger.Info("    => Deleting obsolete RoleBinding", "namespace", found.Namespace, "name", found.Name)
+		err = r.Delete(context.TODO(), found)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "")
+		}
+	}
+
 	workspaceProperties, workspaceExposure, componentInstanceStatuses, k8sObjects, err := convertToCoreObjects(instance)
 	reconcileStatus.wkspProps = workspaceProperties
 	if err != nil {
@@ -248,8 +336,16 @@ func (r *ReconcileWorkspace) Reconcile(request reconcile.Request) (reconcile.Res
 		return reconcile.Result{}, nil
 	}
 
+	k8sObjects, err = provisionAutomountObjects(r.Client, instance, *workspaceProperties, componentInstanceStatuses, k8sObjects)
+	if err != nil {
+		reqLogger.Error(err, "Error when mounting the labelled Secrets and ConfigMaps")
+		reconcileStatus.failure = err.Error()
+		return reconcile.Result{}, nil
+	}
+
 	reconcileStatus.componentInstanceStatuses = componentInstanceStatuses
-	k8sObjectNames := map[string]struct{}{}
+	k8sObjectKeys := map[objectKey]struct{}{}
+	syncResult := objectsync.Result{}
 
 	reqLogger.Info("Managing K8s Objects")
 	for _, k8sObject := range append(k8sObjects, workspaceExposure) {
@@ -257,9 +353,6 @@ func (r *ReconcileWorkspace) Reconcile(request reconcile.Request) (reconcile.Res
 		if !isMeta {
 			return reconcile.Result{}, errors.NewBadRequest("Converted objects are not valid K8s objects")
 		}
-		k8sObjectNames[k8sObjectAsMetaObject.GetName()] = struct{}{}
-
-		reqLogger.V(1).Info("  - Managing K8s Object", "kind", reflect.TypeOf(k8sObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
 
 		// Set Workspace instance as the owner and controller
 		if err := controllerutil.SetControllerReference(instance, k8sObjectAsMetaObject, r.scheme); err != nil {
@@ -268,141 +361,181 @@ func (r *ReconcileWorkspace) Reconcile(request reconcile.Request) (reconcile.Res
 			return reconcile.Result{}, nil
 		}
 
-		k8sObjectAsMetaObject.SetLabels(map[string]string { "che.workspace_id": workspaceProperties.workspaceId })
-
-		// Check if the k8s Object already exists
+		k8sObjectAsMetaObject.SetLabels(withWorkspaceIdLabel(k8sObjectAsMetaObject.GetLabels(), *workspaceProperties))
 
-		found := reflect.New(reflect.TypeOf(k8sObject).Elem()).Interface().(runtime.Object)
-		err = r.Get(context.TODO(), types.NamespacedName{Name: k8sObjectAsMetaObject.GetName(), Namespace: k8sObjectAsMetaObject.GetNamespace()}, found)
-		if err != nil && errors.IsNotFound(err) {
-			reqLogger.Info("  => Creating " + reflect.TypeOf(k8sObjectAsMetaObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
-			err = r.Create(context.TODO(), k8sObject)
-			if err != nil {
-				reqLogger.Error(err, "Error when creating K8S object: ", "k8sObject", k8sObject)
-				reconcileStatus.failure = err.Error()
-				return reconcile.Result{}, nil
-			}
-			if deployment, isDeployment := k8sObject.(*appsv1.Deployment); isDeployment &&
-				strings.HasSuffix(deployment.GetName(), "."+cheOriginalName) {
-				reconcileStatus.createdWorkspaceObjects = true
-			}
-			continue
-		} else if err != nil {
-			reqLogger.Error(err, "Error when getting K8S object: ", "k8sObject", k8sObjectAsMetaObject)
+		desired, err := r.syncer.ToUnstructured(k8sObject)
+		if err != nil {
+			reqLogger.Error(err, "Error when converting K8S object: ", "k8sObject", k8sObjectAsMetaObject.GetName())
 			reconcileStatus.failure = err.Error()
 			return reconcile.Result{}, nil
 		}
+		gvk := desired.GroupVersionKind()
+		k8sObjectKeys[objectKey{groupKind: gvk.GroupKind(), name: desired.GetName()}] = struct{}{}
+		isMainDeployment := gvk.Kind == "Deployment" && strings.HasSuffix(desired.GetName(), "."+cheOriginalName)
 
-		r.scheme.Default(k8sObject)
-
-		// Update the found object and write the result back if there are any changes
-
-		foundSpecValue := reflect.ValueOf(found).Elem().FieldByName("Spec")
-		k8sObjectSpecValue := reflect.ValueOf(k8sObject).Elem().FieldByName("Spec")
+		reqLogger.V(1).Info("  - Managing K8s Object", "kind", gvk.String(), "name", desired.GetName())
 
-		var foundToUse interface{} = found
-		var newToUse interface{} = k8sObject
-		if foundSpecValue.IsValid() {
-			foundToUse = foundSpecValue.Interface()
-			newToUse = k8sObjectSpecValue.Interface()
+		if workspaceProperties.started {
+			if err := r.restoreReplicas(desired); err != nil {
+				reqLogger.Error(err, "Error when restoring the replicas of K8S object: ", "kind", gvk.String(), "name", desired.GetName())
+				reconcileStatus.failure = err.Error()
+				return reconcile.Result{}, nil
+			}
 		}
 
-		diffOpts := cmp.Options{
-			cmpopts.IgnoreUnexported(resource.Quantity{}),
-			cmpopts.IgnoreFields(corev1.ServiceSpec{}, "ClusterIP", "SessionAffinity", "Type"),
-			cmpopts.IgnoreFields(corev1.Container{}, "TerminationMessagePath", "TerminationMessagePolicy", "ImagePullPolicy"),
-			cmpopts.IgnoreFields(corev1.PodSpec{}, "DNSPolicy", "SecurityContext", "SchedulerName", "DeprecatedServiceAccount", "RestartPolicy", "TerminationGracePeriodSeconds"),
-			cmpopts.IgnoreFields(appsv1.DeploymentStrategy{}, "RollingUpdate"),
-			cmpopts.IgnoreFields(appsv1.DeploymentSpec{}, "RevisionHistoryLimit", "ProgressDeadlineSeconds"),
-			cmpopts.IgnoreFields(corev1.ConfigMapVolumeSource{}, "DefaultMode"),
-			cmpopts.IgnoreFields(corev1.ConfigMap{}, "TypeMeta", "ObjectMeta"),
-			cmp.FilterPath(
-				func(p cmp.Path) bool {
-					s := p.String()
-					return s == "Ports.Protocol"
-				},
-				cmp.Transformer("DefaultTcpProtocol", func(p corev1.Protocol) corev1.Protocol {
-					if p == "" {
-						return corev1.ProtocolTCP
-					}
-					return p
-				})),
+		outcome, err := r.syncer.Apply(desired)
+		if err != nil {
+			reqLogger.Error(err, "Error when applying K8S object: ", "kind", gvk.String(), "name", desired.GetName())
+			reconcileStatus.failure = err.Error()
+			return reconcile.Result{}, nil
 		}
-
-		if !cmp.Equal(foundToUse, newToUse, diffOpts) {
-			reqLogger.V(1).Info("  => Differences: " + cmp.Diff(foundToUse, newToUse, diffOpts...))
-			switch found.(type) {
-			case (*appsv1.Deployment):
-				{
-					found.(*appsv1.Deployment).Spec = k8sObject.(*appsv1.Deployment).Spec
-					if strings.HasSuffix(found.(*appsv1.Deployment).GetName(), "."+cheOriginalName) {
-						reconcileStatus.changedWorkspaceObjects = true
-					}
-				}
-			case (*extensionsv1beta1.Ingress):
-				{
-					found.(*extensionsv1beta1.Ingress).Spec = k8sObject.(*extensionsv1beta1.Ingress).Spec
-				}
-			case (*corev1.Service):
-				{
-					k8sObject.(*corev1.Service).Spec.ClusterIP = found.(*corev1.Service).Spec.ClusterIP
-					found.(*corev1.Service).Spec = k8sObject.(*corev1.Service).Spec
-				}
-			case (*corev1.ConfigMap):
-				{
-					found.(*corev1.ConfigMap).Data = k8sObject.(*corev1.ConfigMap).Data
-					found.(*corev1.ConfigMap).BinaryData = k8sObject.(*corev1.ConfigMap).BinaryData
-				}
-			case (*workspacev1alpha1.WorkspaceExposure):
-				{
-					found.(*workspacev1alpha1.WorkspaceExposure).Spec = k8sObject.(*workspacev1alpha1.WorkspaceExposure).Spec
-				}
+		syncResult.Add(outcome)
+		switch outcome {
+		case objectsync.Created:
+			reqLogger.Info("  => Created "+gvk.Kind, "name", desired.GetName())
+			if isMainDeployment {
+				reconcileStatus.createdWorkspaceObjects = true
 			}
-			reqLogger.Info("  => Updating " +reflect.TypeOf(k8sObjectAsMetaObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
-			err = r.Update(context.TODO(), found)
-			if err != nil {
-				reqLogger.Error(err, "Error when updating K8S object: ", "k8sObject", k8sObjectAsMetaObject)
-				reconcileStatus.failure = err.Error()
-				return reconcile.Result{}, nil
+		case objectsync.Updated:
+			reqLogger.Info("  => Updated "+gvk.Kind, "name", desired.GetName())
+			if isMainDeployment {
+				reconcileStatus.changedWorkspaceObjects = true
 			}
 		}
 	}
 
-	if err != nil {
-		reqLogger.Error(err, "Error during reconcile")
-		reconcileStatus.failure = err.Error()
-		return reconcile.Result{}, nil
-	}
+	reqLogger.V(1).Info("K8s objects synchronized: " + syncResult.String())
 
-	for _, list := range []runtime.Object{
-		&appsv1.DeploymentList{},
-		&corev1.ServiceList{},
-		&extensionsv1beta1.IngressList{},
-		&corev1.ConfigMapList{},
-	} {
-		r.List(context.TODO(), &client.ListOptions{
+	for _, gvk := range managedKinds() {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := r.syncer.Client().List(context.TODO(), &client.ListOptions{
 			Namespace: workspaceProperties.namespace,
 			LabelSelector: labels.SelectorFromSet(labels.Set{
 				"che.workspace_id": workspaceProperties.workspaceId,
 			}),
 		}, list)
-		items := reflect.ValueOf(list).Elem().FieldByName("Items")
-		for i := 0; i < items.Len(); i++ {
-			item := items.Index(i).Addr().Interface()
-			if itemMeta, isMeta := item.(metav1.Object); isMeta {
-				if itemRuntime, isRuntime := item.(runtime.Object); isRuntime {
-					if _, present := k8sObjectNames[itemMeta.GetName()]; !present {
-						log.Info("  => Deleting "+reflect.TypeOf(itemRuntime).Elem().String(), "name", itemMeta.GetName())
-						r.Delete(context.TODO(), itemRuntime)
-						if _, isDeployment := itemRuntime.(*appsv1.Deployment); isDeployment &&
-							strings.HasSuffix(itemMeta.GetName(), "."+cheOriginalName) {
-							reconcileStatus.cleanedWorkspaceObjects = true
-						}
-					}
-				}
+		if err != nil {
+			reqLogger.V(1).Info("Cannot list the workspace objects of kind "+gvk.String(), "error", err.Error())
+			continue
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			if _, present := k8sObjectKeys[objectKey{groupKind: gvk.GroupKind(), name: item.GetName()}]; present {
+				continue
+			}
+			if isRenderedConfigMap(instance, gvk, item.GetName()) {
+				continue
+			}
+			if !workspaceProperties.started && item.GetLabels()[SCALE_TO_ZERO_ON_STOP_LABEL] == "true" {
+				r.scaleToZero(item, reqLogger)
+				continue
+			}
+			if !workspaceProperties.started && statefulKinds[gvk.GroupKind()] {
+				continue
+			}
+			log.Info("  => Deleting "+gvk.Kind, "name", item.GetName())
+			if err := r.syncer.Delete(item); err != nil {
+				reqLogger.Error(err, "Error when deleting K8S object: ", "kind", gvk.String(), "name", item.GetName())
+			}
+			if gvk.Kind == "Deployment" && strings.HasSuffix(item.GetName(), "."+cheOriginalName) {
+				reconcileStatus.cleanedWorkspaceObjects = true
 			}
 		}
 	}
 
 	return reconcile.Result{}, nil
 }
+
+// objectKey identifies an object managed by the workspace controller, whatever its kind
+type objectKey struct {
+	groupKind schema.GroupKind
+	name      string
+}
+
+// Kinds of objects that hold the data of kubernetes components, such as the volumes of a database.
+// They are kept while the workspace is stopped, and only deleted with the workspace, as owned objects.
+var statefulKinds = map[schema.GroupKind]bool{
+	corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim").GroupKind(): true,
+	corev1.SchemeGroupVersion.WithKind("Secret").GroupKind():                true,
+}
+
+// managedKinds returns the kinds of objects that the workspace controller may create,
+// and that should be deleted when they are not part of the workspace anymore.
+func managedKinds() []schema.GroupVersionKind {
+	kinds := []schema.GroupVersionKind{
+		appsv1.SchemeGroupVersion.WithKind("Deployment"),
+		corev1.SchemeGroupVersion.WithKind("Service"),
+		corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+		extensionsv1beta1.SchemeGroupVersion.WithKind("Ingress"),
+	}
+	for _, allowedKind := range controllerConfig.getRecipeAllowedKinds() {
+		alreadyManaged := false
+		for _, kind := range kinds {
+			if kind.GroupKind() == allowedKind.GroupKind() {
+				alreadyManaged = true
+			}
+		}
+		if !alreadyManaged {
+			kinds = append(kinds, allowedKind)
+		}
+	}
+	return kinds
+}
+
+// scaleToZero stops the pods of a Deployment or StatefulSet adopted from a kubernetes component,
+// which is kept while the workspace is stopped.
+// Its number of replicas is recorded, to be restored when the workspace starts again.
+func (r *ReconcileWorkspace) scaleToZero(obj *unstructured.Unstructured, reqLogger logr.Logger) {
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil || (found && replicas == 0) {
+		return
+	}
+	if !found {
+		replicas = 1
+	}
+	reqLogger.Info("  => Scaling to zero "+obj.GetKind(), "name", obj.GetName())
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[REPLICAS_BEFORE_STOP_ANNOTATION] = strconv.FormatInt(replicas, 10)
+	obj.SetAnnotations(annotations)
+	if err := unstructured.SetNestedField(obj.Object, int64(0), "spec", "replicas"); err != nil {
+		reqLogger.Error(err, "")
+		return
+	}
+	if err := r.syncer.Client().Update(context.TODO(), obj); err != nil {
+		reqLogger.Error(err, "Error when scaling to zero K8S object: ", "k8sObject", obj.GetName())
+	}
+}
+
+// restoreReplicas sets the number of replicas that a Deployment or StatefulSet adopted from a kubernetes component
+// had before the workspace was stopped, when the component doesn't set it.
+// Otherwise the object, applied without replicas, would stay scaled to zero.
+func (r *ReconcileWorkspace) restoreReplicas(desired *unstructured.Unstructured) error {
+	if desired.GetLabels()[SCALE_TO_ZERO_ON_STOP_LABEL] != "true" {
+		return nil
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(desired.Object, "spec", "replicas"); found {
+		return nil
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.syncer.Client().Get(context.TODO(), types.NamespacedName{Namespace: desired.GetNamespace(), Name: desired.GetName()}, existing)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recordedReplicas, recorded := existing.GetAnnotations()[REPLICAS_BEFORE_STOP_ANNOTATION]
+	if !recorded {
+		return nil
+	}
+	replicas, err := strconv.ParseInt(recordedReplicas, 10, 64)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(desired.Object, replicas, "spec", "replicas")
+}
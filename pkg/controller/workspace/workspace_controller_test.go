@@ -0,0 +1,21 @@
+package workspace
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconciledAnnotationsChanged(t *testing.T) {
+	metaOld := &metav1.ObjectMeta{Annotations: map[string]string{"other": "a"}}
+
+	if reconciledAnnotationsChanged(metaOld, &metav1.ObjectMeta{Annotations: map[string]string{"other": "b"}}) {
+		t.Error("Expected changes to other annotations to be ignored")
+	}
+	if !reconciledAnnotationsChanged(metaOld, &metav1.ObjectMeta{Annotations: map[string]string{REFRESH_DEVFILE_ANNOTATION: "1"}}) {
+		t.Error("Expected the refresh-devfile annotation change to be reconciled")
+	}
+	if !reconciledAnnotationsChanged(metaOld, &metav1.ObjectMeta{Annotations: map[string]string{RENDER_ANNOTATION: "true"}}) {
+		t.Error("Expected the render annotation change to be reconciled")
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"strconv"
 
 	workspacev1alpha1 "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -109,37 +107,7 @@ func (solver *BasicSolver) CreateOrUpdateExposureObjects(cr CurrentReconcile) (r
 		k8sObjects = append(k8sObjects, &newIngress)
 	}
 
-	return CreateOrUpdate(cr, k8sObjects,
-		cmp.Options{
-			cmpopts.IgnoreFields(corev1.ServiceSpec{}, "ClusterIP", "SessionAffinity", "Type"),
-			cmp.FilterPath(
-				func(p cmp.Path) bool {
-					s := p.String()
-					return s == "Ports.Protocol"
-				},
-				cmp.Transformer("DefaultTcpProtocol", func(p corev1.Protocol) corev1.Protocol {
-					if p == "" {
-						return corev1.ProtocolTCP
-					}
-					return p
-				})),
-		},
-		func(found runtime.Object, new runtime.Object) {
-			switch found.(type) {
-			case (*extensionsv1beta1.Ingress):
-				{
-					found.(*extensionsv1beta1.Ingress).Spec = new.(*extensionsv1beta1.Ingress).Spec
-				}
-			case (*corev1.Service):
-				{
-					new.(*corev1.Service).Spec.ClusterIP = found.(*corev1.Service).Spec.ClusterIP
-					found.(*corev1.Service).Spec = new.(*corev1.Service).Spec
-				}
-			}
-		},
-	)
-
-	return reconcile.Result{}, nil
+	return CreateOrUpdate(cr, k8sObjects)
 }
 
 func (solver *BasicSolver) CheckExposureObjects(cr CurrentReconcile, targetPhase workspacev1alpha1.WorkspaceExposurePhase) (workspacev1alpha1.WorkspaceExposurePhase, reconcile.Result, error) {
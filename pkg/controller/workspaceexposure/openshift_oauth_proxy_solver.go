@@ -1,13 +1,10 @@
 package workspaceexposure
 
 import (
-	"k8s.io/apimachinery/pkg/api/resource"
 	k8sModelUtils "github.com/che-incubator/che-workspace-crd-operator/pkg/controller/modelutils/k8s"
 	"strconv"
 
 	workspacev1alpha1 "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -252,46 +249,7 @@ func (solver *OpenshiftOAuthSolver) CreateOrUpdateExposureObjects(cr CurrentReco
 
 	k8sObjects = append(k8sObjects, solver.CreateRoutes(cr)...)
 
-	return CreateOrUpdate(cr, k8sObjects,
-		cmp.Options{
-				cmpopts.IgnoreUnexported(resource.Quantity{}),
-				cmpopts.IgnoreFields(corev1.ServiceSpec{}, "ClusterIP", "SessionAffinity", "Type"),
-				cmpopts.IgnoreFields(corev1.Container{}, "TerminationMessagePath", "TerminationMessagePolicy", "ImagePullPolicy"),
-				cmpopts.IgnoreFields(corev1.PodSpec{}, "DNSPolicy", "SecurityContext", "SchedulerName", "DeprecatedServiceAccount", "RestartPolicy", "TerminationGracePeriodSeconds"),
-				cmpopts.IgnoreFields(appsv1.DeploymentStrategy{}, "RollingUpdate"),
-				cmpopts.IgnoreFields(appsv1.DeploymentSpec{}, "RevisionHistoryLimit", "ProgressDeadlineSeconds"),
-				cmpopts.IgnoreFields(corev1.ConfigMapVolumeSource{}, "DefaultMode"),
-				cmpopts.IgnoreFields(corev1.ConfigMap{}, "TypeMeta", "ObjectMeta"),
-				cmp.FilterPath(
-				func(p cmp.Path) bool {
-					s := p.String()
-					return s == "Ports.Protocol"
-				},
-				cmp.Transformer("DefaultTcpProtocol", func(p corev1.Protocol) corev1.Protocol {
-					if p == "" {
-						return corev1.ProtocolTCP
-					}
-					return p
-				})),
-		},
-		func(found runtime.Object, new runtime.Object) {
-			switch found.(type) {
-			case (*routeV1.Route):
-				{
-					found.(*routeV1.Route).Spec = new.(*routeV1.Route).Spec
-				}
-			case (*corev1.Service):
-				{
-					new.(*corev1.Service).Spec.ClusterIP = found.(*corev1.Service).Spec.ClusterIP
-					found.(*corev1.Service).Spec = new.(*corev1.Service).Spec
-				}
-			case (*appsv1.Deployment):
-				{
-					found.(*appsv1.Deployment).Spec = new.(*appsv1.Deployment).Spec
-				}
-			}
-		},
-	)
+	return CreateOrUpdate(cr, k8sObjects)
 }
 
 func (solver *OpenshiftOAuthSolver) CheckExposureObjects(cr CurrentReconcile, targetPhase workspacev1alpha1.WorkspaceExposurePhase) (workspacev1alpha1.WorkspaceExposurePhase, reconcile.Result, error) {
@@ -4,7 +4,6 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
-	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/types"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -12,6 +11,7 @@ import (
 	"context"
 
 	workspacev1alpha1 "github.com/che-incubator/che-workspace-crd-operator/pkg/apis/workspace/v1alpha1"
+	"github.com/che-incubator/che-workspace-crd-operator/pkg/controller/objectsync"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -29,13 +29,22 @@ var log = logf.Log.WithName("controller_workspaceexposure")
 // Add creates a new WorkspaceExposure Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	r, err := newReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, r)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
+	syncer, err := objectsync.New(mgr, "workspaceexposure-controller")
+	if err != nil {
+		return nil, err
+	}
 	return &ReconcileWorkspaceExposure{
 		client: mgr.GetClient(),
+		syncer: syncer,
 		scheme: mgr.GetScheme(),
 		solvers: map[string]WorkspaceExposureSolver {
 			"": &BasicSolver{
@@ -45,7 +54,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 				Client: mgr.GetClient(),
 			},
 		},
-	}
+	}, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -96,6 +105,8 @@ type ReconcileWorkspaceExposure struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// Applies the exposure objects with server-side apply
+	syncer *objectsync.Syncer
 	scheme *runtime.Scheme
 	solvers map[string]WorkspaceExposureSolver
 }
@@ -276,7 +287,7 @@ type WorkspaceExposureSolver interface {
 func DeleteExposureObjects(cr CurrentReconcile, objectTypes []runtime.Object) (reconcile.Result, error) {
 	cr.ReqLogger.Info("Deleting K8s objects")
 	for _, list := range objectTypes {
-		cr.Reconcile.client.List(context.TODO(), &client.ListOptions{
+		cr.Reconcile.syncer.Client().List(context.TODO(), &client.ListOptions{
 			Namespace: cr.Instance.Namespace,
 			LabelSelector: labels.SelectorFromSet(labels.Set{
 				"org.eclipse.che.workspace.exposure.workspace_id": cr.Instance.Name,
@@ -291,7 +302,7 @@ func DeleteExposureObjects(cr CurrentReconcile, objectTypes []runtime.Object) (r
 			if itemMeta, isMeta := item.(metav1.Object); isMeta {
 				if itemRuntime, isRuntime := item.(runtime.Object); isRuntime {
 					log.Info("  => Deleting "+reflect.TypeOf(itemRuntime).Elem().String(), "name", itemMeta.GetName())
-					err := cr.Reconcile.client.Delete(context.TODO(), itemRuntime)
+					err := cr.Reconcile.syncer.Delete(itemRuntime)
 					if err != nil {
 						cr.ReqLogger.Error(err, "Error when creating K8S object own by the Workspace Exposure: ", "k8sObject", itemRuntime)
 						return reconcile.Result{}, err
@@ -303,12 +314,13 @@ func DeleteExposureObjects(cr CurrentReconcile, objectTypes []runtime.Object) (r
 	return reconcile.Result{}, nil
 }
 
-func CreateOrUpdate(cr CurrentReconcile, k8sObjects []runtime.Object, diffOpts cmp.Options, replaceFun func(found runtime.Object, new runtime.Object)) (reconcile.Result, error) {
+func CreateOrUpdate(cr CurrentReconcile, k8sObjects []runtime.Object) (reconcile.Result, error) {
 	cr.ReqLogger.Info("Creating K8s objects")
 	reqLogger := cr.ReqLogger
 	instance := cr.Instance
 	r := cr.Reconcile
 
+	syncResult := objectsync.Result{}
 	for _, k8sObject := range k8sObjects {
 		k8sObjectAsMetaObject, isMeta := k8sObject.(metav1.Object)
 		if !isMeta {
@@ -328,48 +340,20 @@ func CreateOrUpdate(cr CurrentReconcile, k8sObjects []runtime.Object, diffOpts c
 			"org.eclipse.che.workspace.exposure.workspace_id": instance.Name,
 		})
 
-		// Check if the k8s Object already exists
-
-		found := reflect.New(reflect.TypeOf(k8sObject).Elem()).Interface().(runtime.Object)
-		err := r.client.Get(context.TODO(), types.NamespacedName{Name: k8sObjectAsMetaObject.GetName(), Namespace: k8sObjectAsMetaObject.GetNamespace()}, found)
-		if err != nil && errors.IsNotFound(err) {
-			reqLogger.Info("  => Creating "+reflect.TypeOf(k8sObjectAsMetaObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
-			err = r.client.Create(context.TODO(), k8sObject)
-			if err != nil {
-				reqLogger.Error(err, "Error when creating K8S object: ", "k8sObject", k8sObject)
-				return reconcile.Result{}, err
-			}
-			continue
-		} else if err != nil {
-			reqLogger.Error(err, "Error when getting K8S object: ", "k8sObject", k8sObjectAsMetaObject)
+		outcome, err := r.syncer.Apply(k8sObject)
+		if err != nil {
+			reqLogger.Error(err, "Error when applying K8S object: ", "k8sObject", k8sObjectAsMetaObject.GetName())
 			return reconcile.Result{}, err
 		}
-
-		r.scheme.Default(k8sObject)
-
-		// Update the found object and write the result back if there are any changes
-
-		foundSpecValue := reflect.ValueOf(found).Elem().FieldByName("Spec")
-		k8sObjectSpecValue := reflect.ValueOf(k8sObject).Elem().FieldByName("Spec")
-
-		var foundToUse interface{} = found
-		var newToUse interface{} = k8sObject
-		if foundSpecValue.IsValid() {
-			foundToUse = foundSpecValue.Interface()
-			newToUse = k8sObjectSpecValue.Interface()
-		}
-
-		if !cmp.Equal(foundToUse, newToUse, diffOpts) {
-			reqLogger.V(1).Info("  => Differences: " + cmp.Diff(foundToUse, newToUse, diffOpts...))
-			replaceFun(found, k8sObject)
-			reqLogger.Info("  => Updating "+reflect.TypeOf(k8sObjectAsMetaObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
-			err = r.client.Update(context.TODO(), found)
-			if err != nil {
-				reqLogger.Error(err, "Error when updating K8S object: ", "k8sObject", k8sObjectAsMetaObject)
-				return reconcile.Result{}, err
-			}
+		syncResult.Add(outcome)
+		switch outcome {
+		case objectsync.Created:
+			reqLogger.Info("  => Created "+reflect.TypeOf(k8sObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
+		case objectsync.Updated:
+			reqLogger.Info("  => Updated "+reflect.TypeOf(k8sObject).Elem().String(), "name", k8sObjectAsMetaObject.GetName())
 		}
 	}
+	reqLogger.V(1).Info("K8s objects synchronized: " + syncResult.String())
 
 	return reconcile.Result{}, nil
 }